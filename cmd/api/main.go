@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	routes "github.com/ty4g1/gamescout_backend/internal/api"
 	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/experiments"
 	"github.com/ty4g1/gamescout_backend/internal/repository"
 	"github.com/ty4g1/gamescout_backend/internal/services"
 )
@@ -24,10 +25,46 @@ func main() {
 	}
 	defer dbpool.Close()
 
+	if err := repository.EnsureSchema(context.Background(), dbpool); err != nil {
+		log.Fatalf("Unable to set up database schema: %v\n", err)
+	}
+
+	pgVector := cfg.SearchBackend == "pgvector"
+	if pgVector {
+		if err := repository.EnablePgvector(context.Background(), dbpool, services.FeatureDim(cfg)); err != nil {
+			log.Fatalf("Unable to set up pgvector: %v\n", err)
+		}
+	}
+
 	// Create repositories
-	gr := repository.NewGamesRepository(dbpool)
+	gr := repository.NewGamesRepository(dbpool, pgVector, cfg.HNSWEfSearch)
 	gmr := repository.NewGameMediaRepository(dbpool)
-	ur := repository.NewUserRepository(dbpool)
+	ur := repository.NewUserRepository(dbpool, pgVector)
+	ir := repository.NewImpressionRepository(dbpool)
+	sr := repository.NewSwipeRepository(dbpool)
+	itr := repository.NewInterestRepository(dbpool)
+	simr := repository.NewSimilarityRepository(dbpool)
+	exr := repository.NewExclusionRepository(dbpool)
+	er := repository.NewExperimentRepository(dbpool)
+	rsr := repository.NewSessionRepository(dbpool)
+
+	registry, err := experiments.Load(cfg.ExperimentsFile)
+	if err != nil {
+		log.Fatalf("Unable to load experiments: %v\n", err)
+	}
+
+	searcher := services.NewSearcher(cfg, gr)
+	index, hasIndex := searcher.(*services.IndexSearcher)
+	if hasIndex {
+		fmt.Println("Building ANN index...")
+		start := time.Now()
+		if err := index.Build(context.Background()); err != nil {
+			log.Fatalf("Unable to build ANN index: %v\n", err)
+		}
+		fmt.Printf("ANN index built in %v\n", time.Since(start))
+	}
+
+	cache := services.NewRecommendationCache(cfg)
 
 	go func() {
 		pop := services.NewPopulator(cfg)
@@ -36,12 +73,51 @@ func main() {
 			start := time.Now()
 			pop.Populate(gr, gmr)
 			fmt.Printf("Database population completed successfully in %v!\n", time.Since(start))
+			if hasIndex {
+				if err := index.Refresh(context.Background()); err != nil {
+					log.Printf("Error refreshing ANN index: %v\n", err)
+				}
+			}
+			cache.Purge()
 			fmt.Println("Next population will run in 24 hours...")
 			time.Sleep(24 * time.Hour)
 		}
 	}()
 
-	router := routes.SetupRouter(gr, gmr, ur)
+	cf := services.NewCollaborativeFilter(cfg, sr, simr, gr)
+	go func() {
+		for {
+			start := time.Now()
+			if pairs, err := cf.Build(context.Background()); err != nil {
+				log.Printf("Error building item similarity table: %v\n", err)
+			} else {
+				fmt.Printf("Item similarity table rebuilt with %d pairs in %v\n", pairs, time.Since(start))
+				cache.Purge()
+			}
+			time.Sleep(cfg.CFRebuildInterval)
+		}
+	}()
+
+	go func() {
+		for {
+			time.Sleep(max(cfg.RecSessionTTL, time.Minute))
+			if _, err := rsr.DeleteExpired(context.Background()); err != nil {
+				log.Printf("Error deleting expired recommendation sessions: %v\n", err)
+			}
+		}
+	}()
+
+	pipeline, err := services.NewPipeline(cfg, searcher, cf, gr)
+	if err != nil {
+		log.Fatalf("Unable to set up ranking pipeline: %v\n", err)
+	}
+
+	interests := services.NewInterestBuilder(cfg, gr, sr, itr, cache)
+	go interests.Run(context.Background(), cfg.InterestRebuildDelay)
+
+	pu := services.NewPreferenceUpdater(cfg, ur, gr, interests, cache)
+
+	router := routes.SetupRouter(cfg, gr, gmr, ur, ir, sr, itr, exr, rsr, cache, searcher, pipeline, pu, registry, er)
 
 	fmt.Println("Starting server...")
 
@@ -0,0 +1,268 @@
+// Command evaluate replays historical swipes against a catalog snapshot to
+// measure ranking strategies offline, without a database or the embedding
+// microservice.
+//
+// Each user's swipes are split by time: the last -holdout share is held out
+// and the games liked in it are what a strategy should recommend, given the
+// earlier swipes. Strategies are scored by precision@k, recall@k, NDCG@k,
+// catalog coverage and novelty, and the results are written as JSON and a
+// markdown table.
+//
+// Usage:
+//
+//	go run ./cmd/evaluate -games games.json -swipes swipes.json -k 10,20
+//
+// games.json is a list of games with their feature vectors and swipes.json a
+// list of swipes, both of which can be exported from the database:
+//
+//	psql "$DATABASE_URL" -Atc "SELECT json_agg(json_build_object('appid', appid,
+//	  'price', price, 'release_date', release_date, 'genres', genres, 'tags', tags,
+//	  'positive', positive, 'negative', negative, 'platforms', platforms,
+//	  'developers', developers, 'parent_appid', parent_appid,
+//	  'feature_vector', feature_vector::real[])) FROM Games" > games.json
+//	psql "$DATABASE_URL" -Atc "SELECT json_agg(json_build_object('user', cookie_id,
+//	  'appid', appid, 'action', action, 'created_at', created_at))
+//	  FROM swipe_events" > swipes.json
+//
+// The preference half-life, swipe weights, collaborative filtering and ranking
+// weights are read from the same environment variables as the API.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type snapshotGame struct {
+	AppID         int            `json:"appid"`
+	Name          string         `json:"name"`
+	Price         int            `json:"price"`
+	ReleaseDate   time.Time      `json:"release_date"`
+	Genres        []string       `json:"genres"`
+	Tags          map[string]int `json:"tags"`
+	Positive      int            `json:"positive"`
+	Negative      int            `json:"negative"`
+	Platforms     []string       `json:"platforms"`
+	Developers    []string       `json:"developers"`
+	ParentAppID   int            `json:"parent_appid"`
+	FeatureVector []float64      `json:"feature_vector"`
+}
+
+type snapshotSwipe struct {
+	User      string             `json:"user"`
+	AppID     int                `json:"appid"`
+	Action    models.SwipeAction `json:"action"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// split is one user's swipes before the holdout and the games they liked
+// after it.
+type split struct {
+	user  string
+	train []models.SwipeEvent
+	test  map[int]bool
+}
+
+func main() {
+	gamesPath := flag.String("games", "games.json", "catalog snapshot")
+	swipesPath := flag.String("swipes", "swipes.json", "swipe history")
+	kFlag := flag.String("k", "10,20", "comma separated cutoffs")
+	strategiesFlag := flag.String("strategies", "", "comma separated strategies to evaluate, all by default")
+	holdout := flag.Float64("holdout", 0.2, "share of each user's latest swipes to hold out")
+	seed := flag.Int64("seed", 1, "seed for the random strategy")
+	jsonPath := flag.String("json", "evaluation.json", "where to write the JSON results, empty to skip")
+	markdownPath := flag.String("markdown", "", "where to write the markdown table, stdout if empty")
+	flag.Parse()
+
+	cfg := config.NewConfig()
+
+	ks, err := parseInts(*kFlag)
+	if err != nil {
+		log.Fatalf("Invalid cutoffs %q: %v\n", *kFlag, err)
+	}
+	names := strategyNames()
+	if *strategiesFlag != "" {
+		names = strings.Split(*strategiesFlag, ",")
+	}
+	for _, name := range names {
+		if _, ok := strategies[name]; !ok {
+			log.Fatalf("Unknown strategy %q, expected one of %s\n", name, strings.Join(strategyNames(), ", "))
+		}
+	}
+	if *holdout <= 0 || *holdout >= 1 {
+		log.Fatalf("Holdout must be between 0 and 1, got %v\n", *holdout)
+	}
+
+	games, err := loadGames(*gamesPath)
+	if err != nil {
+		log.Fatalf("Unable to load catalog: %v\n", err)
+	}
+	swipes, err := loadSwipes(*swipesPath)
+	if err != nil {
+		log.Fatalf("Unable to load swipes: %v\n", err)
+	}
+
+	splits, train := splitSwipes(swipes, *holdout)
+	if len(splits) == 0 {
+		log.Fatalf("No user has swipes both before and likes after the holdout\n")
+	}
+	log.Printf("Evaluating %d users against %d games\n", len(splits), len(games))
+
+	env := newEnv(cfg, games, splits, train, *seed)
+	results := make([]result, 0, len(names)*len(ks))
+	for _, name := range names {
+		ranked, err := env.rankAll(strategies[name], maxInt(ks))
+		if err != nil {
+			log.Fatalf("Strategy %s failed: %v\n", name, err)
+		}
+		for _, k := range ks {
+			results = append(results, env.evaluate(name, k, ranked))
+		}
+	}
+
+	report := report{Users: len(splits), Games: len(games), Holdout: *holdout, Results: results}
+	if *jsonPath != "" {
+		if err := writeJSON(*jsonPath, report); err != nil {
+			log.Fatalf("Unable to write %s: %v\n", *jsonPath, err)
+		}
+	}
+	if *markdownPath == "" {
+		fmt.Print(report.Markdown())
+	} else if err := os.WriteFile(*markdownPath, []byte(report.Markdown()), 0o644); err != nil {
+		log.Fatalf("Unable to write %s: %v\n", *markdownPath, err)
+	}
+}
+
+func loadGames(path string) ([]models.Game, error) {
+	var snapshot []snapshotGame
+	if err := readJSON(path, &snapshot); err != nil {
+		return nil, err
+	}
+
+	games := make([]models.Game, 0, len(snapshot))
+	for _, g := range snapshot {
+		games = append(games, models.Game{
+			AppId:         g.AppID,
+			Name:          g.Name,
+			Price:         g.Price,
+			ReleaseDate:   g.ReleaseDate,
+			Genres:        g.Genres,
+			Tags:          g.Tags,
+			Positive:      g.Positive,
+			Negative:      g.Negative,
+			Platforms:     g.Platforms,
+			Developers:    g.Developers,
+			ParentAppId:   g.ParentAppID,
+			FeatureVector: g.FeatureVector,
+		})
+	}
+	return games, nil
+}
+
+func loadSwipes(path string) ([]models.SwipeEvent, error) {
+	var snapshot []snapshotSwipe
+	if err := readJSON(path, &snapshot); err != nil {
+		return nil, err
+	}
+
+	swipes := make([]models.SwipeEvent, 0, len(snapshot))
+	for _, s := range snapshot {
+		if !s.Action.Valid() {
+			return nil, fmt.Errorf("unknown swipe action %q", s.Action)
+		}
+		swipes = append(swipes, models.SwipeEvent{UserID: s.User, AppID: s.AppID, Action: s.Action, CreatedAt: s.CreatedAt})
+	}
+	return swipes, nil
+}
+
+// splitSwipes holds out the latest share of each user's swipes, keeping the
+// users who have something to learn from and a game they went on to like
+// that they hadn't swiped before. It also returns every user's swipes before
+// their holdout, oldest first, including those of users who aren't kept.
+func splitSwipes(swipes []models.SwipeEvent, holdout float64) ([]split, map[string][]models.SwipeEvent) {
+	byUser := make(map[string][]models.SwipeEvent)
+	for _, s := range swipes {
+		byUser[s.UserID] = append(byUser[s.UserID], s)
+	}
+
+	var splits []split
+	train := make(map[string][]models.SwipeEvent, len(byUser))
+	for user, events := range byUser {
+		sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+
+		cut := len(events) - int(math.Ceil(holdout*float64(len(events))))
+		if cut < 1 {
+			continue
+		}
+		train[user] = events[:cut]
+
+		seen := make(map[int]bool, cut)
+		for _, e := range events[:cut] {
+			seen[e.AppID] = true
+		}
+		test := make(map[int]bool)
+		for _, e := range events[cut:] {
+			if e.Action.Liked() && !seen[e.AppID] {
+				test[e.AppID] = true
+			}
+		}
+		if len(test) == 0 {
+			continue
+		}
+
+		splits = append(splits, split{user: user, train: events[:cut], test: test})
+	}
+
+	sort.Slice(splits, func(i, j int) bool { return splits[i].user < splits[j].user })
+	return splits, train
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func parseInts(s string) ([]int, error) {
+	var ints []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("%d is not positive", n)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+func maxInt(ints []int) int {
+	m := 0
+	for _, n := range ints {
+		m = max(m, n)
+	}
+	return m
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type result struct {
+	Strategy  string  `json:"strategy"`
+	K         int     `json:"k"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	NDCG      float64 `json:"ndcg"`
+	// Share of the catalog recommended to anyone
+	Coverage float64 `json:"coverage"`
+	// Mean self-information of recommended games, in bits: how few users had
+	// liked them
+	Novelty float64 `json:"novelty"`
+}
+
+type report struct {
+	Users   int      `json:"users"`
+	Games   int      `json:"games"`
+	Holdout float64  `json:"holdout"`
+	Results []result `json:"results"`
+}
+
+// evaluate scores the first k games ranked for each user against the games
+// they went on to like, averaging the per-user metrics.
+func (e *env) evaluate(name string, k int, ranked map[string][]int) result {
+	var precision, recall, ndcg, novelty float64
+	recommended := make(map[int]bool)
+	shown := 0
+
+	for _, s := range e.splits {
+		top := ranked[s.user][:min(k, len(ranked[s.user]))]
+
+		hits, dcg := 0, 0.0
+		for i, appId := range top {
+			if s.test[appId] {
+				hits++
+				dcg += 1 / math.Log2(float64(i+2))
+			}
+			recommended[appId] = true
+			novelty += -math.Log2(float64(e.likes[appId]+1) / float64(len(e.splits)+1))
+			shown++
+		}
+		idcg := 0.0
+		for i := range min(k, len(s.test)) {
+			idcg += 1 / math.Log2(float64(i+2))
+		}
+
+		precision += float64(hits) / float64(k)
+		recall += float64(hits) / float64(len(s.test))
+		ndcg += dcg / idcg
+	}
+
+	users := float64(len(e.splits))
+	r := result{
+		Strategy:  name,
+		K:         k,
+		Precision: precision / users,
+		Recall:    recall / users,
+		NDCG:      ndcg / users,
+	}
+	if len(e.games) > 0 {
+		r.Coverage = float64(len(recommended)) / float64(len(e.games))
+	}
+	if shown > 0 {
+		r.Novelty = novelty / float64(shown)
+	}
+	return r
+}
+
+// Markdown renders the results as a table, one row per strategy and cutoff.
+func (r report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d users, %d games, latest %.0f%% of swipes held out\n\n", r.Users, r.Games, r.Holdout*100)
+	b.WriteString("| strategy | k | precision | recall | ndcg | coverage | novelty |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "| %s | %d | %.4f | %.4f | %.4f | %.4f | %.2f |\n",
+			res.Strategy, res.K, res.Precision, res.Recall, res.NDCG, res.Coverage, res.Novelty)
+	}
+	return b.String()
+}
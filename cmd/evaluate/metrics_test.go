@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+// fixture is two users evaluated against a four game catalog. a went on to
+// like games 1 and 2 and b game 3; game 4 was liked by both before their
+// holdout, games 1 and 3 by one of them.
+func fixture() *env {
+	return &env{
+		games: []models.Game{{AppId: 1}, {AppId: 2}, {AppId: 3}, {AppId: 4}},
+		splits: []split{
+			{user: "a", test: map[int]bool{1: true, 2: true}},
+			{user: "b", test: map[int]bool{3: true}},
+		},
+		likes: map[int]int{1: 1, 3: 1, 4: 2},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ranked := map[string][]int{
+		"a": {1, 4, 2},
+		"b": {4, 3},
+	}
+
+	got := fixture().evaluate("fixture", 2, ranked)
+
+	// a hits game 1 first out of 2 relevant, b hits game 3 second out of 1
+	ndcgA := 1 / (1 + 1/math.Log2(3))
+	ndcgB := (1 / math.Log2(3)) / 1
+	// Games liked by one of the two users carry -log2(2/3) bits, game 4 none
+	novelty := 2 * -math.Log2(2.0/3) / 4
+
+	want := result{
+		Strategy:  "fixture",
+		K:         2,
+		Precision: (0.5 + 0.5) / 2,
+		Recall:    (0.5 + 1) / 2,
+		NDCG:      (ndcgA + ndcgB) / 2,
+		Coverage:  3.0 / 4,
+		Novelty:   novelty,
+	}
+	assertResult(t, got, want)
+}
+
+func TestEvaluateShortRanking(t *testing.T) {
+	// Rankings shorter than k still count k slots against precision
+	ranked := map[string][]int{
+		"a": {2},
+		"b": {},
+	}
+
+	got := fixture().evaluate("fixture", 3, ranked)
+
+	// a hits game 2 first out of 2 relevant; b recommends nothing
+	ndcgA := 1 / (1 + 1/math.Log2(3))
+
+	want := result{
+		Strategy:  "fixture",
+		K:         3,
+		Precision: (1.0 / 3) / 2,
+		Recall:    0.5 / 2,
+		NDCG:      ndcgA / 2,
+		Coverage:  1.0 / 4,
+		Novelty:   -math.Log2(1.0 / 3),
+	}
+	assertResult(t, got, want)
+}
+
+func TestEvaluateNothingRanked(t *testing.T) {
+	got := fixture().evaluate("fixture", 10, map[string][]int{})
+
+	want := result{Strategy: "fixture", K: 10}
+	assertResult(t, got, want)
+}
+
+func assertResult(t *testing.T, got result, want result) {
+	t.Helper()
+
+	if got.Strategy != want.Strategy || got.K != want.K {
+		t.Errorf("got strategy %q at k %d, want %q at k %d", got.Strategy, got.K, want.Strategy, want.K)
+	}
+	metrics := []struct {
+		name      string
+		got, want float64
+	}{
+		{"precision", got.Precision, want.Precision},
+		{"recall", got.Recall, want.Recall},
+		{"ndcg", got.NDCG, want.NDCG},
+		{"coverage", got.Coverage, want.Coverage},
+		{"novelty", got.Novelty, want.Novelty},
+	}
+	for _, m := range metrics {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s = %f, want %f", m.name, m.got, m.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math/rand"
+	"slices"
+	"sort"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
+	"github.com/ty4g1/gamescout_backend/internal/services"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// strategy builds the pipeline a strategy ranks with, from the API's
+// generators over the catalog held in memory.
+type strategy func(e *env) (*ranking.Pipeline, error)
+
+// strategies are the ranking strategies that can be evaluated. Add a strategy
+// here to compare it against the others.
+var strategies = map[string]strategy{
+	// What the API serves with the current config
+	"configured": func(e *env) (*ranking.Pipeline, error) {
+		scorer, err := services.NewScorer(e.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return e.pipeline(e.cfg.RankGenerators, scorer)
+	},
+	"vector": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"vector"}, ranking.WeightedScorer{"similarity": 1})
+	},
+	"blended": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"vector"}, services.ConfiguredWeights(e.cfg))
+	},
+	"cf": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"cf"}, ranking.WeightedScorer{"cf": 1})
+	},
+	"hybrid": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"vector", "cf"}, ranking.WeightedScorer{"similarity": 1, "cf": 1})
+	},
+	"popular": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"popular"}, ranking.WeightedScorer{"popularity": 1})
+	},
+	"random": func(e *env) (*ranking.Pipeline, error) {
+		return e.pipeline([]string{"random"}, ranking.WeightedScorer{})
+	},
+}
+
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// env holds what every strategy is evaluated against: the catalog and what
+// was known before each user's holdout.
+type env struct {
+	cfg     *config.Config
+	games   []models.Game
+	vectors map[int][]float64
+	splits  []split
+	// Collaborative filtering scores by user, from everyone's training likes
+	cfScores map[string]map[int]float64
+	// Number of users who liked each game before their holdout
+	likes map[int]int
+	seed  int64
+}
+
+// newEnv builds the environment for splits. Collaborative filtering learns
+// from train, every user's swipes before their holdout, so users without
+// likes to evaluate still count as neighbours.
+func newEnv(cfg *config.Config, games []models.Game, splits []split, train map[string][]models.SwipeEvent, seed int64) *env {
+	vectors := make(map[int][]float64, len(games))
+	for _, game := range games {
+		vectors[game.AppId] = game.FeatureVector
+	}
+
+	// ItemSimilarities wants likes grouped by user, most recent first
+	users := slices.Sorted(maps.Keys(train))
+	var likes []models.SwipeEvent
+	for _, user := range users {
+		for _, appId := range services.RecentLikes(train[user], 0) {
+			likes = append(likes, models.SwipeEvent{UserID: user, AppID: appId, Action: models.SwipeLike})
+		}
+	}
+	likers := make(map[int]int)
+	for _, s := range splits {
+		for _, appId := range services.RecentLikes(s.train, 0) {
+			likers[appId]++
+		}
+	}
+	neighbors := make(map[int][]models.ItemSimilarity)
+	for _, sim := range services.ItemSimilarities(likes, max(cfg.CFMinSupport, 1), cfg.CFShrinkage, cfg.CFMaxNeighbors, cfg.CFMaxUserLikes) {
+		neighbors[sim.AppID] = append(neighbors[sim.AppID], sim)
+	}
+
+	cfScores := make(map[string]map[int]float64, len(splits))
+	for _, s := range splits {
+		liked := services.RecentLikes(s.train, cfg.CFMaxUserLikes)
+		var userNeighbors []models.ItemSimilarity
+		for _, appId := range liked {
+			userNeighbors = append(userNeighbors, neighbors[appId]...)
+		}
+		cfScores[s.user] = services.CFScores(liked, userNeighbors)
+	}
+
+	return &env{
+		cfg:      cfg,
+		games:    games,
+		vectors:  vectors,
+		splits:   splits,
+		cfScores: cfScores,
+		likes:    likers,
+		seed:     seed,
+	}
+}
+
+// pipeline is services.BuildPipeline over the catalog held in memory.
+func (e *env) pipeline(generators []string, scorer ranking.Scorer) (*ranking.Pipeline, error) {
+	catalog := &memoryCatalog{games: e.games, rng: rand.New(rand.NewSource(e.seed))}
+	cf := &memoryCF{games: e.games, scores: e.cfScores}
+	return services.BuildPipeline(e.cfg, services.NewScanSearcher(catalog), cf, catalog, generators, scorer)
+}
+
+// rankAll ranks k games for every user from their preference at the start
+// of their holdout, leaving out the games they had already swiped. Games are
+// picked from the ranking as the API serves them with the default options,
+// except for exploration, which is random.
+func (e *env) rankAll(build strategy, k int) (map[string][]int, error) {
+	pipeline, err := build(e)
+	if err != nil {
+		return nil, err
+	}
+	opts := services.DefaultServeOptions(e.cfg)
+
+	ranked := make(map[string][]int, len(e.splits))
+	for _, s := range e.splits {
+		now := s.train[len(s.train)-1].CreatedAt
+		preference := services.PreferenceFromSwipes(e.cfg, s.train, e.vectors, now)
+
+		exclude := make([]int, 0, len(s.train))
+		for _, swipe := range s.train {
+			exclude = append(exclude, swipe.AppID)
+		}
+
+		req := &ranking.Request{
+			UserID:  s.user,
+			Vectors: [][]float64{preference},
+			Filter:  &models.GameFilter{Exclude: exclude},
+		}
+		req.PoolSize = services.PoolSize(e.cfg, pipeline, req, k, opts)
+		scored, err := pipeline.Rank(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		served := ranking.Diversify(scored, k, opts.Diversity, opts.MaxPerTag)
+
+		appIds := make([]int, 0, len(served))
+		for _, game := range served {
+			appIds = append(appIds, game.AppId)
+		}
+		ranked[s.user] = appIds
+	}
+	return ranked, nil
+}
+
+// errFiltered makes TopK skip games the request filters out.
+var errFiltered = errors.New("filtered out")
+
+// memoryCatalog is services.Catalog over the catalog snapshot.
+type memoryCatalog struct {
+	games []models.Game
+	rng   *rand.Rand
+}
+
+func (mc *memoryCatalog) GetAll(ctx context.Context, filter *models.GameFilter) ([]models.Game, error) {
+	matches := filter.Matcher()
+	var games []models.Game
+	for i := range mc.games {
+		if matches(&mc.games[i]) {
+			games = append(games, mc.games[i])
+		}
+	}
+	return games, nil
+}
+
+func (mc *memoryCatalog) GetPopular(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error) {
+	matches := filter.Matcher()
+	top := utils.TopK(len(mc.games), limit, func(i int) (float64, error) {
+		if !matches(&mc.games[i]) {
+			return 0, errFiltered
+		}
+		return float64(mc.games[i].Positive + mc.games[i].Negative), nil
+	})
+
+	games := make([]models.Game, 0, len(top))
+	for _, r := range top {
+		games = append(games, mc.games[r.Index])
+	}
+	return games, nil
+}
+
+func (mc *memoryCatalog) GetRandom(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error) {
+	matches := filter.Matcher()
+	var games []models.Game
+	for _, i := range mc.rng.Perm(len(mc.games)) {
+		if len(games) == limit {
+			break
+		}
+		if matches(&mc.games[i]) {
+			games = append(games, mc.games[i])
+		}
+	}
+	return games, nil
+}
+
+// memoryCF is services.CFSource over the scores computed from training likes.
+type memoryCF struct {
+	games  []models.Game
+	scores map[string]map[int]float64
+}
+
+func (mcf *memoryCF) Scores(ctx context.Context, id string) (map[int]float64, error) {
+	return mcf.scores[id], nil
+}
+
+func (mcf *memoryCF) Recommend(ctx context.Context, id string, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+	scores := mcf.scores[id]
+	matches := filter.Matcher()
+	top := utils.TopK(len(mcf.games), k, func(i int) (float64, error) {
+		score, ok := scores[mcf.games[i].AppId]
+		if !ok || !matches(&mcf.games[i]) {
+			return 0, errFiltered
+		}
+		return score, nil
+	})
+
+	scored := make([]models.ScoredGame, 0, len(top))
+	for _, r := range top {
+		scored = append(scored, models.ScoredGame{Game: mcf.games[r.Index], Score: r.Score})
+	}
+	return scored, nil
+}
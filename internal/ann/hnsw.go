@@ -0,0 +1,345 @@
+package ann
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Result is a key returned by a search along with its inner product with the
+// query vector.
+type Result struct {
+	Key   int
+	Score float64
+}
+
+type node struct {
+	key       int
+	vector    []float64
+	neighbors [][]int32
+	deleted   bool
+}
+
+// Index is a Hierarchical Navigable Small World graph over vectors compared
+// by inner product. Vectors are expected to be normalized, which makes the
+// inner product a cosine similarity.
+//
+// Deleting a key only marks its node so the graph stays navigable; callers
+// should rebuild the index once Deleted becomes a large share of the nodes.
+type Index struct {
+	mu             sync.RWMutex
+	m              int
+	mMax0          int
+	efConstruction int
+	levelMult      float64
+	rng            *rand.Rand
+	nodes          []*node
+	keys           map[int]int32
+	entry          int32
+	maxLevel       int
+	deleted        int
+}
+
+// New returns an empty index where every node keeps up to m neighbours per
+// layer (2m on the bottom layer), searching efConstruction candidates when
+// inserting.
+func New(m int, efConstruction int) *Index {
+	m = max(m, 2)
+	return &Index{
+		m:              m,
+		mMax0:          2 * m,
+		efConstruction: max(efConstruction, m),
+		levelMult:      1 / math.Log(float64(m)),
+		rng:            rand.New(rand.NewSource(1)),
+		keys:           make(map[int]int32),
+		entry:          -1,
+	}
+}
+
+// Len returns the number of live keys in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.keys)
+}
+
+// Deleted returns the number of deleted nodes still kept in the graph.
+func (idx *Index) Deleted() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.deleted
+}
+
+// Vector returns the vector stored for key.
+func (idx *Index) Vector(key int) ([]float64, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	id, ok := idx.keys[key]
+	if !ok {
+		return nil, false
+	}
+	return idx.nodes[id].vector, true
+}
+
+// Delete removes key from search results.
+func (idx *Index) Delete(key int) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.delete(key)
+}
+
+func (idx *Index) delete(key int) bool {
+	id, ok := idx.keys[key]
+	if !ok {
+		return false
+	}
+	idx.nodes[id].deleted = true
+	delete(idx.keys, key)
+	idx.deleted++
+	return true
+}
+
+// Add inserts vector under key, replacing any vector already stored for it.
+func (idx *Index) Add(key int, vector []float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.delete(key)
+
+	level := int(-math.Log(1-idx.rng.Float64()) * idx.levelMult)
+	id := int32(len(idx.nodes))
+	n := &node{
+		key:       key,
+		vector:    vector,
+		neighbors: make([][]int32, level+1),
+	}
+	idx.nodes = append(idx.nodes, n)
+	idx.keys[key] = id
+
+	if idx.entry < 0 {
+		idx.entry = id
+		idx.maxLevel = level
+		return
+	}
+
+	ep := idx.entry
+	for l := idx.maxLevel; l > level; l-- {
+		ep = idx.greedy(vector, ep, l)
+	}
+
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayer(vector, ep, idx.efConstruction, l, nil)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		mMax := idx.m
+		if l == 0 {
+			mMax = idx.mMax0
+		}
+
+		n.neighbors[l] = idx.selectNeighbors(candidates, idx.m)
+		for _, nb := range n.neighbors[l] {
+			neighbor := idx.nodes[nb]
+			neighbor.neighbors[l] = append(neighbor.neighbors[l], id)
+			if len(neighbor.neighbors[l]) > mMax {
+				neighbor.neighbors[l] = idx.prune(neighbor, l, mMax)
+			}
+		}
+		ep = candidates[0].id
+	}
+
+	if level > idx.maxLevel {
+		idx.maxLevel = level
+		idx.entry = id
+	}
+}
+
+// Search returns up to k live keys accepted by accept (nil accepts every key)
+// with the highest inner product with query, best first. ef is the size of
+// the dynamic candidate list and trades speed for recall; it is raised to k
+// if smaller.
+func (idx *Index) Search(query []float64, k int, ef int, accept func(key int) bool) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if idx.entry < 0 || k <= 0 {
+		return nil
+	}
+
+	ep := idx.entry
+	for l := idx.maxLevel; l > 0; l-- {
+		ep = idx.greedy(query, ep, l)
+	}
+
+	candidates := idx.searchLayer(query, ep, max(ef, k), 0, accept)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	results := make([]Result, 0, len(candidates))
+	for _, c := range candidates {
+		results = append(results, Result{Key: idx.nodes[c.id].key, Score: c.score})
+	}
+	return results
+}
+
+// greedy walks layer l from ep towards query and returns the closest node
+// found. Deleted nodes are still used for navigation.
+func (idx *Index) greedy(query []float64, ep int32, l int) int32 {
+	best := dot(query, idx.nodes[ep].vector)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range idx.nodes[ep].neighbors[l] {
+			if score := dot(query, idx.nodes[nb].vector); score > best {
+				best = score
+				ep = nb
+				changed = true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer runs a best-first search of layer l starting from ep and
+// returns up to ef live nodes accepted by accept, best first. Rejected nodes
+// are traversed but never returned.
+func (idx *Index) searchLayer(query []float64, ep int32, ef int, l int, accept func(key int) bool) []candidate {
+	ok := func(id int32) bool {
+		n := idx.nodes[id]
+		return !n.deleted && (accept == nil || accept(n.key))
+	}
+
+	visited := make([]uint64, len(idx.nodes)/64+1)
+	visit := func(id int32) bool {
+		word, bit := id/64, uint64(1)<<(id%64)
+		if visited[word]&bit != 0 {
+			return false
+		}
+		visited[word] |= bit
+		return true
+	}
+
+	start := candidate{id: ep, score: dot(query, idx.nodes[ep].vector)}
+	visit(ep)
+
+	frontier := &maxHeap{start}
+	results := &minHeap{}
+	if ok(ep) {
+		heap.Push(results, start)
+	}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+		if results.Len() >= ef && c.score < (*results)[0].score {
+			break
+		}
+
+		for _, nb := range idx.nodes[c.id].neighbors[l] {
+			if !visit(nb) {
+				continue
+			}
+			score := dot(query, idx.nodes[nb].vector)
+			if results.Len() < ef || score > (*results)[0].score {
+				heap.Push(frontier, candidate{id: nb, score: score})
+				if ok(nb) {
+					heap.Push(results, candidate{id: nb, score: score})
+					if results.Len() > ef {
+						heap.Pop(results)
+					}
+				}
+			}
+		}
+	}
+
+	out := []candidate(*results)
+	sort.Slice(out, func(i, j int) bool { return out[i].score > out[j].score })
+	return out
+}
+
+// selectNeighbors picks up to m neighbours from candidates (sorted best
+// first) using the HNSW heuristic: a candidate is kept only if it is closer
+// to the query than to every neighbour already kept, which spreads edges
+// across clusters. Remaining slots are filled with the best discarded ones.
+func (idx *Index) selectNeighbors(candidates []candidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var discarded []int32
+
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		keep := true
+		for _, s := range selected {
+			if dot(idx.nodes[c.id].vector, idx.nodes[s].vector) > c.score {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.id)
+		} else {
+			discarded = append(discarded, c.id)
+		}
+	}
+
+	for _, id := range discarded {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, id)
+	}
+	return selected
+}
+
+func (idx *Index) prune(n *node, l int, mMax int) []int32 {
+	candidates := make([]candidate, 0, len(n.neighbors[l]))
+	for _, nb := range n.neighbors[l] {
+		candidates = append(candidates, candidate{id: nb, score: dot(n.vector, idx.nodes[nb].vector)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return idx.selectNeighbors(candidates, mMax)
+}
+
+func dot(a []float64, b []float64) float64 {
+	n := min(len(a), len(b))
+	a, b = a[:n], b[:n]
+	var res float64
+	for i := range a {
+		res += a[i] * b[i]
+	}
+	return res
+}
+
+type candidate struct {
+	id    int32
+	score float64
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].score > h[j].score }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
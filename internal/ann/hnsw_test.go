@@ -0,0 +1,157 @@
+package ann
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomVectors(rng *rand.Rand, n int, dim int) [][]float64 {
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		v := make([]float64, dim)
+		var norm float64
+		for j := range v {
+			v[j] = rng.NormFloat64()
+			norm += v[j] * v[j]
+		}
+		norm = math.Sqrt(norm)
+		for j := range v {
+			v[j] /= norm
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func bruteForce(vectors map[int][]float64, query []float64, k int) []int {
+	keys := make([]int, 0, len(vectors))
+	for key := range vectors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return dot(query, vectors[keys[i]]) > dot(query, vectors[keys[j]])
+	})
+	return keys[:min(k, len(keys))]
+}
+
+func TestSearchRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	vectors := randomVectors(rng, 2000, 16)
+
+	idx := New(16, 100)
+	stored := make(map[int][]float64, len(vectors))
+	for i, v := range vectors {
+		idx.Add(i, v)
+		stored[i] = v
+	}
+
+	const k = 10
+	hits, total := 0, 0
+	for _, query := range randomVectors(rng, 50, 16) {
+		found := make(map[int]bool, k)
+		for _, res := range idx.Search(query, k, 100, nil) {
+			found[res.Key] = true
+		}
+		for _, key := range bruteForce(stored, query, k) {
+			if found[key] {
+				hits++
+			}
+			total++
+		}
+	}
+
+	if recall := float64(hits) / float64(total); recall < 0.95 {
+		t.Errorf("recall@%d = %.3f, want at least 0.95", k, recall)
+	}
+}
+
+func TestSearchOrderAndAccept(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	idx := New(8, 50)
+	for i, v := range randomVectors(rng, 500, 8) {
+		idx.Add(i, v)
+	}
+
+	query := randomVectors(rng, 1, 8)[0]
+	even := func(key int) bool { return key%2 == 0 }
+	results := idx.Search(query, 20, 50, even)
+
+	if len(results) != 20 {
+		t.Fatalf("got %d results, want 20", len(results))
+	}
+	for i, res := range results {
+		if !even(res.Key) {
+			t.Errorf("result %d has rejected key %d", i, res.Key)
+		}
+		if i > 0 && res.Score > results[i-1].Score {
+			t.Errorf("result %d scores %f above result %d's %f", i, res.Score, i-1, results[i-1].Score)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(rng, 300, 8)
+	idx := New(8, 50)
+	for i, v := range vectors {
+		idx.Add(i, v)
+	}
+
+	if !idx.Delete(5) {
+		t.Fatal("Delete(5) = false, want true")
+	}
+	if idx.Delete(5) {
+		t.Error("second Delete(5) = true, want false")
+	}
+	if idx.Len() != 299 || idx.Deleted() != 1 {
+		t.Errorf("Len, Deleted = %d, %d, want 299, 1", idx.Len(), idx.Deleted())
+	}
+	if _, ok := idx.Vector(5); ok {
+		t.Error("Vector(5) found a deleted key")
+	}
+
+	// Searching for the deleted vector itself must not return it
+	for _, res := range idx.Search(vectors[5], 10, 50, nil) {
+		if res.Key == 5 {
+			t.Fatal("search returned deleted key 5")
+		}
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	vectors := randomVectors(rng, 301, 8)
+	idx := New(8, 50)
+	for i, v := range vectors[:300] {
+		idx.Add(i, v)
+	}
+
+	replacement := vectors[300]
+	idx.Add(5, replacement)
+
+	if idx.Len() != 300 || idx.Deleted() != 1 {
+		t.Errorf("Len, Deleted = %d, %d, want 300, 1", idx.Len(), idx.Deleted())
+	}
+	if v, ok := idx.Vector(5); !ok || &v[0] != &replacement[0] {
+		t.Error("Vector(5) is not the replacement vector")
+	}
+
+	results := idx.Search(replacement, 10, 50, nil)
+	if len(results) == 0 || results[0].Key != 5 {
+		t.Fatalf("top result for the replacement vector is %v, want key 5", results)
+	}
+	for _, res := range results[1:] {
+		if res.Key == 5 {
+			t.Error("key 5 returned more than once")
+		}
+	}
+}
+
+func TestSearchEmpty(t *testing.T) {
+	idx := New(8, 50)
+	if results := idx.Search([]float64{1, 0}, 5, 50, nil); len(results) != 0 {
+		t.Errorf("empty index returned %v", results)
+	}
+}
@@ -0,0 +1,77 @@
+package ann
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats tracks query latency, sampled recall and build times of an index.
+type Stats struct {
+	mu            sync.Mutex
+	queries       int64
+	totalLatency  time.Duration
+	maxLatency    time.Duration
+	recallSamples int64
+	recallSum     float64
+	lastBuild     time.Time
+	buildDuration time.Duration
+}
+
+type StatsSnapshot struct {
+	Size            int       `json:"size"`
+	Deleted         int       `json:"deleted"`
+	Queries         int64     `json:"queries"`
+	AvgLatencyMs    float64   `json:"avg_latency_ms"`
+	MaxLatencyMs    float64   `json:"max_latency_ms"`
+	RecallSamples   int64     `json:"recall_samples"`
+	AvgRecall       float64   `json:"avg_recall"`
+	LastBuild       time.Time `json:"last_build"`
+	BuildDurationMs float64   `json:"build_duration_ms"`
+}
+
+func (s *Stats) RecordQuery(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.totalLatency += latency
+	s.maxLatency = max(s.maxLatency, latency)
+}
+
+// RecordRecall records the share of the exact top-k a sampled query returned.
+func (s *Stats) RecordRecall(recall float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recallSamples++
+	s.recallSum += recall
+}
+
+func (s *Stats) RecordBuild(at time.Time, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastBuild = at
+	s.buildDuration = duration
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := StatsSnapshot{
+		Queries:         s.queries,
+		MaxLatencyMs:    milliseconds(s.maxLatency),
+		RecallSamples:   s.recallSamples,
+		LastBuild:       s.lastBuild,
+		BuildDurationMs: milliseconds(s.buildDuration),
+	}
+	if s.queries > 0 {
+		snapshot.AvgLatencyMs = milliseconds(s.totalLatency) / float64(s.queries)
+	}
+	if s.recallSamples > 0 {
+		snapshot.AvgRecall = s.recallSum / float64(s.recallSamples)
+	}
+	return snapshot
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ty4g1/gamescout_backend/internal/experiments"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+)
+
+type ExperimentHandler struct {
+	experiments *experiments.Registry
+	er          *repository.ExperimentRepository
+}
+
+func NewExperimentHandler(registry *experiments.Registry, er *repository.ExperimentRepository) *ExperimentHandler {
+	return &ExperimentHandler{
+		experiments: registry,
+		er:          er,
+	}
+}
+
+func (eh *ExperimentHandler) GetExperiments(c *gin.Context) {
+	list := eh.experiments.Experiments()
+	c.JSON(http.StatusOK, gin.H{"experiments": list, "count": len(list)})
+}
+
+func (eh *ExperimentHandler) GetReport(c *gin.Context) {
+	experiment, ok := eh.experiments.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return
+	}
+
+	variants, err := eh.er.Report(c.Request.Context(), experiment.Name)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get experiment report"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"experiment": experiment, "variants": variants})
+}
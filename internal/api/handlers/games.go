@@ -1,113 +1,115 @@
 package handlers
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math/rand"
 	"net/http"
 	"net/url"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/experiments"
 	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
 	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/services"
 	"github.com/ty4g1/gamescout_backend/internal/utils"
 )
 
+const (
+	// Most likes and dislikes an anonymous recommendation request may carry
+	anonymousMaxSwipes = 200
+	// How many of the user's latest likes explanations are drawn from
+	explainMaxLikes = 50
+	// How many liked games an explanation names
+	explainNearest = 3
+)
+
+type GameWithMedia struct {
+	models.Game
+	Media       *models.GameMedia    `json:"media,omitempty"`
+	Slot        string               `json:"slot,omitempty"`
+	Score       map[string]float64   `json:"score,omitempty"`
+	Explanation *ranking.Explanation `json:"explanation,omitempty"`
+	// Predicted score of each member, for group recommendations
+	MemberScores map[string]float64 `json:"member_scores,omitempty"`
+}
+
 type GameHandler struct {
-	gr  *repository.GameRepository
-	gmr *repository.GameMediaRepository
-	ur  *repository.UserRepository
+	gr          *repository.GameRepository
+	gmr         *repository.GameMediaRepository
+	ur          *repository.UserRepository
+	ir          *repository.ImpressionRepository
+	sr          *repository.SwipeRepository
+	itr         *repository.InterestRepository
+	exr         *repository.ExclusionRepository
+	sessions    *repository.SessionRepository
+	cache       *services.RecommendationCache
+	searcher    services.Searcher
+	pipeline    *ranking.Pipeline
+	experiments *experiments.Registry
+	cfg         *config.Config
 }
 
-func NewGameHandler(gr *repository.GameRepository, gmr *repository.GameMediaRepository, ur *repository.UserRepository) *GameHandler {
+func NewGameHandler(gr *repository.GameRepository, gmr *repository.GameMediaRepository, ur *repository.UserRepository, ir *repository.ImpressionRepository, sr *repository.SwipeRepository, itr *repository.InterestRepository, exr *repository.ExclusionRepository, sessions *repository.SessionRepository, cache *services.RecommendationCache, searcher services.Searcher, pipeline *ranking.Pipeline, registry *experiments.Registry, cfg *config.Config) *GameHandler {
 	return &GameHandler{
-		gr:  gr,
-		gmr: gmr,
-		ur:  ur,
+		gr:          gr,
+		gmr:         gmr,
+		ur:          ur,
+		ir:          ir,
+		sr:          sr,
+		itr:         itr,
+		exr:         exr,
+		sessions:    sessions,
+		cache:       cache,
+		searcher:    searcher,
+		pipeline:    pipeline,
+		experiments: registry,
+		cfg:         cfg,
 	}
 }
 
 func (gh *GameHandler) GetRandomGames(c *gin.Context) {
-	// Parse limit
-	limit := 10
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
-	}
-
-	priceRange := &models.PriceRange{Min: 0, Max: 10000} // Always create with defaults
-
-	// Override min if provided
-	if minStr := c.Query("min_price"); minStr != "" {
-		if min, err := strconv.Atoi(minStr); err == nil && min >= 0 {
-			priceRange.Min = min
-		}
-	}
-
-	// Override max if provided
-	if maxStr := c.Query("max_price"); maxStr != "" {
-		if max, err := strconv.Atoi(maxStr); err == nil && max >= 0 {
-			priceRange.Max = max
-		}
-	}
-
-	// Parse release date
-	var releaseDate *models.ReleaseDate
-	if dateStr := c.Query("release_date"); dateStr != "" {
-		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-			isBefore := c.Query("before") == "true"
-			releaseDate = &models.ReleaseDate{Date: date, IsBefore: isBefore}
-		}
-	}
-
-	// Parse arrays (handle empty strings as nil)
-	var tags, genres, platforms []string
-	if tagsStr := c.Query("tags"); tagsStr != "" {
-		tags = strings.Split(tagsStr, ",")
-		// Trim whitespace and URL decode
-		for i := range tags {
-			tags[i] = strings.TrimSpace(tags[i])
-			if decoded, err := url.QueryUnescape(tags[i]); err == nil {
-				tags[i] = decoded
-			}
+	limit := parseLimit(c)
+	filter := parseGameFilter(c)
+
+	// Skip games the user has already seen when they identify themselves
+	id := c.Query("id")
+	if id != "" {
+		seen, err := gh.seenAppIDs(c.Request.Context(), id)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
 		}
-	}
-
-	if genresStr := c.Query("genres"); genresStr != "" {
-		genres = strings.Split(genresStr, ",")
-		for i := range genres {
-			genres[i] = strings.TrimSpace(genres[i])
-			if decoded, err := url.QueryUnescape(genres[i]); err == nil {
-				genres[i] = decoded
-			}
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user history"})
+			return
 		}
-	}
+		filter.Exclude = seen
 
-	if platformsStr := c.Query("platforms"); platformsStr != "" {
-		platforms = strings.Split(platformsStr, ",")
-		for i := range platforms {
-			platforms[i] = strings.TrimSpace(platforms[i])
-			if decoded, err := url.QueryUnescape(platforms[i]); err == nil {
-				platforms[i] = decoded
-			}
+		if err := gh.applyExclusions(c.Request.Context(), id, filter); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
+			return
 		}
 	}
 
-	games, err := gh.gr.GetRandom(c.Request.Context(), limit, priceRange, releaseDate, tags, genres, platforms)
+	games, err := gh.gr.GetRandom(c.Request.Context(), limit, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
 		return
 	}
 
-	type GameWithMedia struct {
-		models.Game
-		Media *models.GameMedia `json:"media,omitempty"`
-	}
-
 	var response []GameWithMedia
 	for _, game := range games {
 		gameWithMedia := GameWithMedia{Game: game}
@@ -123,10 +125,31 @@ func (gh *GameHandler) GetRandomGames(c *gin.Context) {
 		response = append(response, gameWithMedia)
 	}
 
+	if id != "" {
+		impressions := make([]models.Impression, 0, len(games))
+		for _, game := range games {
+			impressions = append(impressions, models.Impression{UserID: id, AppID: game.AppId, Source: "random"})
+		}
+		gh.recordImpressions(c.Request.Context(), impressions)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"games": response, "count": len(games)})
 }
 
 func (gh *GameHandler) GetRecommendations(c *gin.Context) {
+	// Users in an experiment get their variant's strategy for every option
+	// the request leaves unset. This has to run before the query is parsed.
+	assignment, inExperiment := gh.experiments.Assign(c.Request.URL.Query().Get("id"))
+	if inExperiment {
+		query := c.Request.URL.Query()
+		for key, value := range assignment.Params {
+			if !query.Has(key) {
+				query.Set(key, value)
+			}
+		}
+		c.Request.URL.RawQuery = query.Encode()
+	}
+
 	// Parse id
 	var id string
 	if idStr := c.Query("id"); idStr != "" {
@@ -136,130 +159,547 @@ func (gh *GameHandler) GetRecommendations(c *gin.Context) {
 		return
 	}
 
-	// Parse limit
-	limit := 10
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
+	limit := parseLimit(c)
+	debug := c.Query("debug") == "true"
+	explain := c.Query("explain") == "true"
+
+	// Later pages come from the stored session, whatever the other options
+	if cursor := c.Query("cursor"); cursor != "" {
+		gh.nextPage(c, id, cursor, limit, debug, explain)
+		return
 	}
 
-	priceRange := &models.PriceRange{Min: 0, Max: 10000} // Always create with defaults
+	filter := parseGameFilter(c)
 
-	// Override min if provided
-	if minStr := c.Query("min_price"); minStr != "" {
-		if min, err := strconv.Atoi(minStr); err == nil && min >= 0 {
-			priceRange.Min = min
+	// Parse diversity options
+	opts := services.DefaultServeOptions(gh.cfg)
+	if diversityStr := c.Query("diversity"); diversityStr != "" {
+		if parsed, err := strconv.ParseFloat(diversityStr, 64); err == nil && parsed >= 0 && parsed <= 1 {
+			opts.Diversity = parsed
 		}
 	}
 
-	// Override max if provided
-	if maxStr := c.Query("max_price"); maxStr != "" {
-		if max, err := strconv.Atoi(maxStr); err == nil && max >= 0 {
-			priceRange.Max = max
+	if maxPerTagStr := c.Query("max_per_tag"); maxPerTagStr != "" {
+		if parsed, err := strconv.Atoi(maxPerTagStr); err == nil && parsed > 0 {
+			opts.MaxPerTag = parsed
 		}
 	}
 
-	// Parse release date
-	var releaseDate *models.ReleaseDate
-	if dateStr := c.Query("release_date"); dateStr != "" {
-		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-			isBefore := c.Query("before") == "true"
-			releaseDate = &models.ReleaseDate{Date: date, IsBefore: isBefore}
+	// Parse exploration share
+	if exploreStr := c.Query("explore"); exploreStr != "" {
+		if parsed, err := strconv.ParseFloat(exploreStr, 64); err == nil && parsed >= 0 && parsed <= 1 {
+			opts.ExploreRate = parsed
 		}
 	}
+	thompson := gh.cfg.ExploreStrategy == "thompson"
+
+	// Clients that page through recommendations get a whole session ranked
+	// up front so later pages keep its order; others just the first page
+	paginate := c.Query("paginate") == "true"
+	size := limit
+	if paginate {
+		size = max(limit, gh.cfg.RecSessionSize)
+	}
 
-	// Parse arrays (handle empty strings as nil)
-	var tags, genres, platforms []string
-	if tagsStr := c.Query("tags"); tagsStr != "" {
-		tags = strings.Split(tagsStr, ",")
-		// Trim whitespace
-		for i := range tags {
-			tags[i] = strings.TrimSpace(tags[i])
+	req := &ranking.Request{
+		UserID:     id,
+		Filter:     filter,
+		Generators: parseList(c.Query("generators")),
+		Weights:    parseWeights(c),
+	}
+	// "cf" ranks by co-likes from other users instead of content similarity
+	if c.Query("mode") == "cf" {
+		similarity, ok := req.Weights["similarity"]
+		if !ok {
+			similarity = gh.cfg.RankWeightSimilarity
 		}
+		req.Generators = []string{"cf"}
+		req.Weights["cf"], req.Weights["similarity"] = similarity, 0
 	}
 
-	if genresStr := c.Query("genres"); genresStr != "" {
-		genres = strings.Split(genresStr, ",")
-		for i := range genres {
-			genres[i] = strings.TrimSpace(genres[i])
+	req.PoolSize = services.PoolSize(gh.cfg, gh.pipeline, req, size, opts)
+
+	seen, err := gh.seenAppIDs(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user history"})
+		return
+	}
+
+	filter.Exclude = seen
+
+	if err := gh.applyExclusions(c.Request.Context(), id, filter); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
+		return
+	}
+
+	// Rankings of the same request are reused, minus the games seen since.
+	// Changing exclusions invalidates them, so only appids need checking.
+	excluded := make(map[int]bool, len(filter.Exclude))
+	for _, appId := range filter.Exclude {
+		excluded[appId] = true
+	}
+	key := recommendationKey(c, req.PoolSize)
+	cached, version, hit := gh.cache.Get(id, key, func(appId int) bool { return !excluded[appId] }, limit)
+
+	var scored []models.ScoredGame
+	if hit {
+		req.Vectors = cached.Vectors
+		scored, err = gh.cachedGames(c.Request.Context(), cached.Candidates)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+			return
+		}
+	} else {
+		preferenceVector, err := gh.ur.GetUserPreference(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user preferences: %v", err)})
+			return
+		}
+
+		req.Vectors, err = gh.userVectors(c.Request.Context(), id, preferenceVector)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user interests"})
+			return
+		}
+
+		scored, err = gh.pipeline.Rank(c.Request.Context(), req)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+			return
 		}
+		gh.cache.Put(id, key, version, req.Vectors, scored)
 	}
 
-	if platformsStr := c.Query("platforms"); platformsStr != "" {
-		platforms = strings.Split(platformsStr, ",")
-		for i := range platforms {
-			platforms[i] = strings.TrimSpace(platforms[i])
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	nExplore := ranking.ExploreSlots(size, opts.ExploreRate, rng)
+
+	exploit := ranking.Diversify(scored, size-nExplore, opts.Diversity, opts.MaxPerTag)
+
+	var explore []models.ScoredGame
+	if nExplore > 0 {
+		explore, err = gh.exploreGames(c.Request.Context(), nExplore, thompson, req, scored, exploit, rng)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exploration games"})
+			return
 		}
 	}
 
-	games, err := gh.gr.GetAll(c.Request.Context(), priceRange, releaseDate, tags, genres, platforms)
+	deck := ranking.Mix(exploit, explore, rng)
+
+	page, next := deck[:min(limit, len(deck))], ""
+	if paginate && len(deck) > limit {
+		next = gh.startSession(c.Request.Context(), id, deck, assignment, limit)
+	}
+	gh.servePage(c, id, page, assignment, next, debug, explain)
+}
+
+// cachedGames looks the games of cached candidates up again, keeping their
+// order, scores and breakdowns. Games no longer in the catalog are dropped.
+func (gh *GameHandler) cachedGames(ctx context.Context, candidates []services.CachedCandidate) ([]models.ScoredGame, error) {
+	appIds := make([]int, 0, len(candidates))
+	for _, c := range candidates {
+		appIds = append(appIds, c.AppID)
+	}
+
+	games, err := gh.gr.GetByAppIDs(ctx, appIds)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+		return nil, err
+	}
+	byAppID := make(map[int]models.Game, len(games))
+	for _, game := range games {
+		byAppID[game.AppId] = game
+	}
+
+	scored := make([]models.ScoredGame, 0, len(candidates))
+	for _, c := range candidates {
+		if game, ok := byAppID[c.AppID]; ok {
+			scored = append(scored, models.ScoredGame{Game: game, Score: c.Score, Breakdown: c.Breakdown})
+		}
+	}
+	return scored, nil
+}
+
+// startSession stores the ranked deck for later pages and returns the cursor
+// of the page after the first limit games. Without a session the first page
+// can still be served, so errors are only logged and give no cursor.
+func (gh *GameHandler) startSession(ctx context.Context, id string, deck []ranking.DeckItem, assignment experiments.Assignment, limit int) string {
+	items := make([]models.SessionItem, 0, len(deck))
+	for _, item := range deck {
+		items = append(items, models.SessionItem{AppID: item.AppId, Slot: item.Slot, Score: item.Breakdown})
+	}
+
+	sessionId, err := gh.sessions.Create(ctx, &models.RecSession{
+		UserID:     id,
+		Items:      items,
+		Experiment: assignment.Experiment,
+		Variant:    assignment.Variant,
+		ExpiresAt:  time.Now().Add(gh.cfg.RecSessionTTL),
+	})
+	if err != nil {
+		log.Printf("Error storing recommendation session: %v\n", err)
+		return ""
+	}
+	return encodeCursor(sessionId, limit)
+}
+
+// nextPage serves the page of a stored session the cursor points at.
+func (gh *GameHandler) nextPage(c *gin.Context, id string, cursor string, limit int, debug bool, explain bool) {
+	sessionId, offset, err := decodeCursor(cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
 		return
 	}
 
-	preferenceVector, err := gh.ur.GetUserPreference(c.Request.Context(), id)
+	session, err := gh.sessions.Get(c.Request.Context(), sessionId, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user preferences: %v", err)})
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recommendation session"})
 		return
 	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendation session not found or expired"})
+		return
+	}
+
+	end := min(offset+limit, len(session.Items))
+	items := session.Items[min(offset, end):end]
 
-	swipeHistory, err := gh.ur.GetUserSwipes(c.Request.Context(), id)
+	appIds := make([]int, 0, len(items))
+	for _, item := range items {
+		appIds = append(appIds, item.AppID)
+	}
+	games, err := gh.gr.GetByAppIDs(c.Request.Context(), appIds)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user swipe history"})
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
 		return
 	}
+	byAppId := make(map[int]models.Game, len(games))
+	for _, game := range games {
+		byAppId[game.AppId] = game
+	}
 
-	swipeSet := make(map[int]bool, 100)
-	for _, appId := range swipeHistory[len(swipeHistory)-100:] {
-		swipeSet[appId] = true
+	// Games removed from the catalog since are dropped from the page
+	page := make([]ranking.DeckItem, 0, len(items))
+	for _, item := range items {
+		if game, ok := byAppId[item.AppID]; ok {
+			scored := models.ScoredGame{Game: game, Score: item.Score["final"], Breakdown: item.Score}
+			page = append(page, ranking.DeckItem{ScoredGame: scored, Slot: item.Slot})
+		}
 	}
 
-	filteredGames := utils.Filter(games, func(g models.Game) bool {
-		return !swipeSet[g.AppId]
-	})
+	next := ""
+	if end < len(session.Items) {
+		next = encodeCursor(session.ID, end)
+	}
+	assignment := experiments.Assignment{Experiment: session.Experiment, Variant: session.Variant}
+	gh.servePage(c, id, page, assignment, next, debug, explain)
+}
+
+// servePage records impressions for a page of recommendations and responds
+// with it, along with the cursor of the next page if there is one.
+func (gh *GameHandler) servePage(c *gin.Context, id string, page []ranking.DeckItem, assignment experiments.Assignment, next string, debug bool, explain bool) {
+	impressions := make([]models.Impression, 0, len(page))
+	for _, item := range page {
+		impressions = append(impressions, models.Impression{
+			UserID:     id,
+			AppID:      item.AppId,
+			Source:     "recommend",
+			Slot:       item.Slot,
+			Experiment: assignment.Experiment,
+			Variant:    assignment.Variant,
+		})
+	}
+	gh.recordImpressions(c.Request.Context(), impressions)
 
-	sort.Slice(filteredGames, func(i, j int) bool {
-		// Comparison logic based on the key
-		s1, err := utils.ComputeSimilarity(preferenceVector, filteredGames[i].FeatureVector)
+	response, err := gh.withMedia(c.Request.Context(), page, debug)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game media"})
+		return
+	}
+
+	if explain {
+		liked, err := gh.likedGames(c.Request.Context(), id)
 		if err != nil {
-			return false
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get liked games"})
+			return
 		}
-		s2, err := utils.ComputeSimilarity(preferenceVector, filteredGames[j].FeatureVector)
-		if err != nil {
-			return true
+		for i := range response {
+			explanation := ranking.Explain(response[i].Game, liked, explainNearest)
+			response[i].Explanation = &explanation
 		}
-		return s1 > s2
-	})
+	}
 
-	if len(filteredGames) > limit {
-		games = filteredGames[:limit]
-	} else {
-		games = filteredGames
+	body := gin.H{"games": response, "count": len(response)}
+	if next != "" {
+		body["next_cursor"] = next
 	}
+	if assignment.Experiment != "" {
+		body["experiment"] = assignment
+	}
+	c.JSON(http.StatusOK, body)
+}
 
-	type GameWithMedia struct {
-		models.Game
-		Media *models.GameMedia `json:"media,omitempty"`
+// GetAnonymousRecommendations ranks games for likes and dislikes given in
+// the request body instead of a stored user. The preference is computed as
+// UpdatePreference would for a new user and is not kept.
+func (gh *GameHandler) GetAnonymousRecommendations(c *gin.Context) {
+	// Parse likes and dislikes
+	var body struct {
+		Likes    []int `json:"likes"`
+		Dislikes []int `json:"dislikes"`
 	}
 
-	var response []GameWithMedia
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(body.Likes)+len(body.Dislikes) == 0 || len(body.Likes)+len(body.Dislikes) > anonymousMaxSwipes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Between 1 and %d likes and dislikes are required", anonymousMaxSwipes)})
+		return
+	}
+
+	limit := parseLimit(c)
+	filter := parseGameFilter(c)
+	debug := c.Query("debug") == "true"
+
+	now := time.Now()
+	swipes := make([]models.SwipeEvent, 0, len(body.Likes)+len(body.Dislikes))
+	for _, appId := range body.Likes {
+		swipes = append(swipes, models.SwipeEvent{AppID: appId, Action: models.SwipeLike, CreatedAt: now})
+	}
+	for _, appId := range body.Dislikes {
+		swipes = append(swipes, models.SwipeEvent{AppID: appId, Action: models.SwipeDislike, CreatedAt: now})
+	}
+
+	appIds := append(slices.Clone(body.Likes), body.Dislikes...)
+	vectors, err := gh.gr.GetFeatureVecMap(c.Request.Context(), appIds)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game features"})
+		return
+	}
+	if !slices.ContainsFunc(appIds, func(appId int) bool {
+		return len(vectors[appId]) == services.FeatureDim(gh.cfg)
+	}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "None of the given games have a feature vector"})
+		return
+	}
+	filter.Exclude = appIds
+
+	req := &ranking.Request{
+		Vectors:    [][]float64{services.PreferenceFromSwipes(gh.cfg, swipes, vectors, now)},
+		Filter:     filter,
+		Generators: parseList(c.Query("generators")),
+		Weights:    parseWeights(c),
+	}
+	req.PoolSize = services.PoolSize(gh.cfg, gh.pipeline, req, limit, services.ServeOptions{})
+
+	scored, err := gh.pipeline.Rank(c.Request.Context(), req)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+		return
+	}
+
+	deck := make([]ranking.DeckItem, 0, limit)
+	for _, game := range scored[:min(limit, len(scored))] {
+		deck = append(deck, ranking.DeckItem{ScoredGame: game})
+	}
+
+	response, err := gh.withMedia(c.Request.Context(), deck, debug)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game media"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"games": response, "count": len(response)})
+}
+
+// likedGames returns the games the user liked most recently, which
+// explanations are drawn from.
+func (gh *GameHandler) likedGames(ctx context.Context, id string) ([]models.Game, error) {
+	events, err := gh.sr.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return gh.gr.GetByAppIDs(ctx, services.RecentLikes(events, explainMaxLikes))
+}
+
+// userVectors returns the vectors to rank candidates against, see
+// services.InterestVectors.
+func (gh *GameHandler) userVectors(ctx context.Context, id string, preferenceVector []float64) ([][]float64, error) {
+	interests, err := gh.itr.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return services.InterestVectors(preferenceVector, interests), nil
+}
+
+// exploreGames picks n games outside of the exploit picks, either at random
+// from the filtered catalog or by Thompson sampling the rest of the pool.
+func (gh *GameHandler) exploreGames(ctx context.Context, n int, thompson bool, req *ranking.Request, pool []models.ScoredGame, exploit []models.ScoredGame, rng *rand.Rand) ([]models.ScoredGame, error) {
+	picked := make(map[int]bool, len(exploit))
+	for _, game := range exploit {
+		picked[game.AppId] = true
+	}
+
+	if thompson {
+		rest := utils.Filter(pool, func(g models.ScoredGame) bool {
+			return !picked[g.AppId]
+		})
+		return ranking.ThompsonPick(rest, n, gh.cfg.ExploreSigma, rng), nil
+	}
+
+	exploreFilter := *req.Filter
+	exploreFilter.Exclude = append(slices.Clone(req.Filter.Exclude), slices.Collect(maps.Keys(picked))...)
+
+	games, err := gh.gr.GetRandom(ctx, n, &exploreFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	explore := make([]models.ScoredGame, 0, len(games))
 	for _, game := range games {
-		gameWithMedia := GameWithMedia{Game: game}
+		explore = append(explore, models.ScoredGame{Game: game})
+	}
+	return gh.pipeline.Score(ctx, req, explore)
+}
 
-		if media, err := gh.gmr.GetByAppID(c.Request.Context(), game.AppId); err != nil {
+// applyExclusions adds the tags, genres, developers and games the user never
+// wants to see to filter.
+func (gh *GameHandler) applyExclusions(ctx context.Context, id string, filter *models.GameFilter) error {
+	exclusions, err := gh.exr.GetByUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	filter.ApplyExclusions(exclusions)
+	return nil
+}
+
+// seenAppIDs returns every game the user has swiped on, plus the games shown
+// to them within the impression window.
+func (gh *GameHandler) seenAppIDs(ctx context.Context, id string) ([]int, error) {
+	swipeHistory, err := gh.ur.GetUserSwipes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	shown, err := gh.ir.GetShownSince(ctx, id, time.Now().Add(-gh.cfg.ImpressionWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(swipeHistory, shown...), nil
+}
+
+// recordImpressions stores what was served. Failing to do so shouldn't fail
+// the request, so errors are only logged.
+func (gh *GameHandler) recordImpressions(ctx context.Context, impressions []models.Impression) {
+	if err := gh.ir.BatchInsert(ctx, impressions); err != nil {
+		log.Printf("Error recording impressions: %v\n", err)
+	}
+}
+
+// withMedia attaches each game's media for the response, along with its
+// score breakdown when debug is set.
+func (gh *GameHandler) withMedia(ctx context.Context, deck []ranking.DeckItem, debug bool) ([]GameWithMedia, error) {
+	response := make([]GameWithMedia, 0, len(deck))
+	for _, item := range deck {
+		media, err := gh.gmr.GetByAppID(ctx, item.AppId)
+		if err != nil {
+			return nil, err
+		}
+		gameWithMedia := GameWithMedia{Game: item.Game, Media: media, Slot: item.Slot}
+		if debug {
+			gameWithMedia.Score = item.Breakdown
+		}
+		response = append(response, gameWithMedia)
+	}
+	return response, nil
+}
+
+func (gh *GameHandler) GetSimilarGames(c *gin.Context) {
+	// Parse appid
+	appId, err := strconv.Atoi(c.Param("appid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appid"})
+		return
+	}
+
+	limit := parseLimit(c)
+	filter := parseGameFilter(c)
+	debug := c.Query("debug") == "true"
+
+	games, err := gh.gr.GetByAppIDs(c.Request.Context(), []int{appId})
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game"})
+		return
+	}
+	if len(games) == 0 || len(games[0].FeatureVector) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+	game := games[0]
+
+	// Never suggest the game itself or its DLC
+	filter.Exclude = append(slices.Clone(game.DLC), game.AppId)
+	filter.ExcludeDLCOf = []int{game.AppId}
+
+	// Skip games the user has already swiped when they identify themselves
+	if id := c.Query("id"); id != "" {
+		swiped, err := gh.ur.GetUserSwipes(c.Request.Context(), id)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
 			log.Println(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game media"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user history"})
 			return
-		} else {
-			gameWithMedia.Media = media
 		}
+		filter.Exclude = append(filter.Exclude, swiped...)
 
-		response = append(response, gameWithMedia)
+		if err := gh.applyExclusions(c.Request.Context(), id, filter); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"games": response, "count": len(games)})
+	scored, err := gh.searcher.Search(c.Request.Context(), game.FeatureVector, limit, filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+		return
+	}
+
+	deck := make([]ranking.DeckItem, 0, len(scored))
+	for _, similar := range scored {
+		similar.Breakdown = map[string]float64{"similarity": similar.Score}
+		deck = append(deck, ranking.DeckItem{ScoredGame: similar})
+	}
+
+	response, err := gh.withMedia(c.Request.Context(), deck, debug)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game media"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"games": response, "count": len(response)})
 }
 
 func (gh *GameHandler) GetTags(c *gin.Context) {
@@ -279,3 +719,127 @@ func (gh *GameHandler) GetGenres(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"genres": genres, "count": len(genres)})
 }
+
+func (gh *GameHandler) GetIndexStats(c *gin.Context) {
+	index, ok := gh.searcher.(*services.IndexSearcher)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ANN index is not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": index.Stats()})
+}
+
+func (gh *GameHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"stats": gh.cache.Stats()})
+}
+
+func parseLimit(c *gin.Context) int {
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	return limit
+}
+
+// recommendationKey identifies the ranking a request asks for, leaving out
+// the options that only apply after ranking.
+func recommendationKey(c *gin.Context, poolSize int) string {
+	query := c.Request.URL.Query()
+	for _, key := range []string{"id", "cursor", "limit", "paginate", "debug", "explain", "diversity", "max_per_tag", "explore"} {
+		query.Del(key)
+	}
+	query.Set("pool", strconv.Itoa(poolSize))
+	return query.Encode()
+}
+
+// encodeCursor makes the opaque cursor of the page starting at offset in a
+// recommendation session.
+func encodeCursor(sessionId string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", sessionId, offset)))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, err
+	}
+	sessionId, offsetStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return "", 0, fmt.Errorf("malformed cursor")
+	}
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("malformed cursor offset %q", offsetStr)
+	}
+	return sessionId, offset, nil
+}
+
+// parseWeights reads ranking feature weights given as w_<feature> query
+// parameters, ignoring negative and malformed ones.
+func parseWeights(c *gin.Context) map[string]float64 {
+	weights := make(map[string]float64)
+	for key, values := range c.Request.URL.Query() {
+		feature, ok := strings.CutPrefix(key, "w_")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if parsed, err := strconv.ParseFloat(values[0], 64); err == nil && parsed >= 0 {
+			weights[feature] = parsed
+		}
+	}
+	return weights
+}
+
+func parseGameFilter(c *gin.Context) *models.GameFilter {
+	priceRange := &models.PriceRange{Min: 0, Max: 10000} // Always create with defaults
+
+	// Override min if provided
+	if minStr := c.Query("min_price"); minStr != "" {
+		if min, err := strconv.Atoi(minStr); err == nil && min >= 0 {
+			priceRange.Min = min
+		}
+	}
+
+	// Override max if provided
+	if maxStr := c.Query("max_price"); maxStr != "" {
+		if max, err := strconv.Atoi(maxStr); err == nil && max >= 0 {
+			priceRange.Max = max
+		}
+	}
+
+	// Parse release date
+	var releaseDate *models.ReleaseDate
+	if dateStr := c.Query("release_date"); dateStr != "" {
+		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
+			isBefore := c.Query("before") == "true"
+			releaseDate = &models.ReleaseDate{Date: date, IsBefore: isBefore}
+		}
+	}
+
+	return &models.GameFilter{
+		PriceRange:  priceRange,
+		ReleaseDate: releaseDate,
+		Tags:        parseList(c.Query("tags")),
+		Genres:      parseList(c.Query("genres")),
+		Platforms:   parseList(c.Query("platforms")),
+	}
+}
+
+// parseList splits a comma separated query value, returning nil for an empty
+// string so the filter is skipped
+func parseList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	items := strings.Split(raw, ",")
+	// Trim whitespace and URL decode
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+		if decoded, err := url.QueryUnescape(items[i]); err == nil {
+			items[i] = decoded
+		}
+	}
+	return items
+}
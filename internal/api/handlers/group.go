@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// Most users a group recommendation can be made for
+const groupMaxMembers = 10
+
+// GetGroupRecommendations finds games for several users to play together,
+// combining each member's predicted score with the chosen strategy. Games
+// any member disliked or excluded are left out, as are games not available
+// on a platform every member has.
+func (gh *GameHandler) GetGroupRecommendations(c *gin.Context) {
+	// Parse ids
+	var ids []string
+	for _, id := range parseList(c.Query("ids")) {
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 || len(ids) > groupMaxMembers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Between 2 and %d user IDs are required", groupMaxMembers)})
+		return
+	}
+
+	strategy := ranking.GroupStrategy(c.DefaultQuery("strategy", string(ranking.GroupAverage)))
+	if !strategy.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown strategy %q", strategy)})
+		return
+	}
+
+	limit := parseLimit(c)
+	filter := parseGameFilter(c)
+	debug := c.Query("debug") == "true"
+	ctx := c.Request.Context()
+
+	platforms, err := gh.ur.GetPlatforms(ctx, ids)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user platforms"})
+		return
+	}
+	if shared, constrained := sharedPlatforms(filter.Platforms, ids, platforms); constrained {
+		if len(shared) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Members share no platform"})
+			return
+		}
+		filter.Platforms = shared
+	}
+
+	vectors := make([][]float64, 0, len(ids))
+	for _, id := range ids {
+		preferenceVector, err := gh.ur.GetUserPreference(ctx, id)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("User %s not found", id)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user preferences: %v", err)})
+			return
+		}
+		vectors = append(vectors, preferenceVector)
+
+		disliked, err := gh.dislikedAppIDs(ctx, id)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user history"})
+			return
+		}
+		filter.Exclude = append(filter.Exclude, disliked...)
+
+		if err := gh.applyExclusions(ctx, id, filter); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
+			return
+		}
+	}
+
+	average, err := utils.SumRows(vectors)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to combine user preferences"})
+		return
+	}
+
+	// Candidates are the games closest to the group's average taste and to
+	// each member's own, so least misery and Borda have games every member
+	// ranks highly to choose from
+	poolSize := limit * max(gh.cfg.RerankPoolFactor, 1)
+	lists := make([][]models.ScoredGame, 0, len(vectors)+1)
+	for _, vector := range append([][]float64{utils.NormalizeVector(average)}, vectors...) {
+		scored, err := gh.searcher.Search(ctx, vector, poolSize, filter)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get games"})
+			return
+		}
+		lists = append(lists, scored)
+	}
+	candidates := ranking.Interleave(lists)
+
+	memberScores := make([][]float64, len(vectors))
+	for m, vector := range vectors {
+		memberScores[m] = make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			memberScores[m][i], _ = utils.ComputeSimilarity(vector, candidate.FeatureVector)
+		}
+	}
+	group := ranking.AggregateGroup(strategy, memberScores)
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return group[order[a]] > group[order[b]] })
+	order = order[:min(limit, len(order))]
+
+	deck := make([]ranking.DeckItem, 0, len(order))
+	for _, i := range order {
+		candidate := candidates[i]
+		candidate.Score = group[i]
+		candidate.Breakdown = map[string]float64{"group": group[i]}
+		deck = append(deck, ranking.DeckItem{ScoredGame: candidate})
+	}
+
+	response, err := gh.withMedia(ctx, deck, debug)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get game media"})
+		return
+	}
+
+	impressions := make([]models.Impression, 0, len(ids)*len(deck))
+	for r, i := range order {
+		response[r].MemberScores = make(map[string]float64, len(ids))
+		for m, id := range ids {
+			response[r].MemberScores[id] = memberScores[m][i]
+			impressions = append(impressions, models.Impression{UserID: id, AppID: candidates[i].AppId, Source: "group"})
+		}
+	}
+	gh.recordImpressions(ctx, impressions)
+
+	c.JSON(http.StatusOK, gin.H{"games": response, "count": len(response), "strategy": strategy, "platforms": filter.Platforms})
+}
+
+// sharedPlatforms returns the platforms every member who set theirs has,
+// narrowed down to requested when given. constrained is false when neither
+// the members nor the request restrict platforms.
+func sharedPlatforms(requested []string, ids []string, platforms map[string][]string) ([]string, bool) {
+	shared := requested
+	constrained := requested != nil
+	for _, id := range ids {
+		userPlatforms, ok := platforms[id]
+		if !ok {
+			continue
+		}
+		if !constrained {
+			shared, constrained = slices.Clone(userPlatforms), true
+			continue
+		}
+		shared = utils.Filter(shared, func(platform string) bool {
+			return slices.Contains(userPlatforms, platform)
+		})
+	}
+	return shared, constrained
+}
+
+// dislikedAppIDs returns the games whose latest swipe by the user was a
+// dislike.
+func (gh *GameHandler) dislikedAppIDs(ctx context.Context, id string) ([]int, error) {
+	events, err := gh.sr.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[int]models.SwipeAction, len(events))
+	for _, event := range events {
+		latest[event.AppID] = event.Action
+	}
+
+	var disliked []int
+	for appId, action := range latest {
+		if action == models.SwipeDislike {
+			disliked = append(disliked, appId)
+		}
+	}
+	return disliked, nil
+}
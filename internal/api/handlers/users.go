@@ -1,23 +1,50 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/experiments"
+	"github.com/ty4g1/gamescout_backend/internal/models"
 	"github.com/ty4g1/gamescout_backend/internal/repository"
-	"github.com/ty4g1/gamescout_backend/internal/utils"
+	"github.com/ty4g1/gamescout_backend/internal/services"
 )
 
+// Most swipes a single undo may revert
+const undoMaxSwipes = 100
+
 type UserHandler struct {
-	ur *repository.UserRepository
-	gr *repository.GameRepository
+	ur          *repository.UserRepository
+	gr          *repository.GameRepository
+	sr          *repository.SwipeRepository
+	itr         *repository.InterestRepository
+	exr         *repository.ExclusionRepository
+	pu          *services.PreferenceUpdater
+	cache       *services.RecommendationCache
+	experiments *experiments.Registry
+	vectorDim   int
 }
 
-func NewUserHandler(ur *repository.UserRepository, gr *repository.GameRepository) *UserHandler {
+func NewUserHandler(ur *repository.UserRepository, gr *repository.GameRepository, sr *repository.SwipeRepository, itr *repository.InterestRepository, exr *repository.ExclusionRepository, pu *services.PreferenceUpdater, cache *services.RecommendationCache, registry *experiments.Registry, cfg *config.Config) *UserHandler {
 	return &UserHandler{
-		ur: ur,
-		gr: gr,
+		ur:          ur,
+		gr:          gr,
+		sr:          sr,
+		itr:         itr,
+		exr:         exr,
+		pu:          pu,
+		cache:       cache,
+		experiments: registry,
+		vectorDim:   services.FeatureDim(cfg),
 	}
 }
 
@@ -32,11 +59,12 @@ func (uh *UserHandler) AddUser(c *gin.Context) {
 		return
 	}
 
-	user, err := uh.ur.AddUser(c.Request.Context(), req.ID)
+	user, err := uh.ur.AddUser(c.Request.Context(), req.ID, uh.vectorDim)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add user"})
 		return
 	}
+	uh.cache.Invalidate(req.ID)
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
@@ -53,59 +81,315 @@ func (uh *UserHandler) UpdatePreference(c *gin.Context) {
 		return
 	}
 
-	err := uh.ur.UpdateUserSwipes(c.Request.Context(), req.ID, append(req.Likes, req.Dislikes...))
+	now := time.Now()
+	swipes := make([]models.SwipeEvent, 0, len(req.Likes)+len(req.Dislikes))
+	for _, appId := range req.Likes {
+		swipes = append(swipes, models.SwipeEvent{UserID: req.ID, AppID: appId, Action: models.SwipeLike, CreatedAt: now})
+	}
+	for _, appId := range req.Dislikes {
+		swipes = append(swipes, models.SwipeEvent{UserID: req.ID, AppID: appId, Action: models.SwipeDislike, CreatedAt: now})
+	}
+	if appId, ok := duplicateSwipe(swipes); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Game %d is swiped more than once", appId)})
+		return
+	}
+
+	uh.tagExperiment(req.ID, swipes)
+	tagRequest(c, swipes)
+
+	preferences, err := uh.pu.Apply(c.Request.Context(), req.ID, swipes)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update user swipes: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update user preferences: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+func (uh *UserHandler) AddSwipes(c *gin.Context) {
+	// Parse id and swipes
+	var req struct {
+		ID     string `json:"id" binding:"required"`
+		Swipes []struct {
+			AppID  int                `json:"appid" binding:"required"`
+			Action models.SwipeAction `json:"action" binding:"required"`
+		} `json:"swipes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	additions, err := uh.gr.GetFeatureVecByAppIDs(c.Request.Context(), req.Likes)
+	now := time.Now()
+	swipes := make([]models.SwipeEvent, 0, len(req.Swipes))
+	for _, swipe := range req.Swipes {
+		if !swipe.Action.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown swipe action %q", swipe.Action)})
+			return
+		}
+		swipes = append(swipes, models.SwipeEvent{UserID: req.ID, AppID: swipe.AppID, Action: swipe.Action, CreatedAt: now})
+	}
+	if appId, ok := duplicateSwipe(swipes); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Game %d is swiped more than once", appId)})
+		return
+	}
+
+	uh.tagExperiment(req.ID, swipes)
+	tagRequest(c, swipes)
+
+	preferences, err := uh.pu.Apply(c.Request.Context(), req.ID, swipes)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feature vectors"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update user preferences: %v", err)})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
 
-	subtractions, err := uh.gr.GetFeatureVecByAppIDs(c.Request.Context(), req.Dislikes)
+// UndoSwipes reverts the user's latest swipes, or those recorded by one
+// request, and recomputes their preference without them.
+func (uh *UserHandler) UndoSwipes(c *gin.Context) {
+	// Parse id and what to undo
+	var req struct {
+		ID        string `json:"id" binding:"required"`
+		Count     int    `json:"count"`
+		RequestID string `json:"request_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > undoMaxSwipes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Count must be between 1 and %d", undoMaxSwipes)})
+		return
+	}
+
+	undone, preferences, err := uh.pu.Undo(c.Request.Context(), req.ID, req.Count, req.RequestID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feature vectors"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to undo swipes: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"undone": undone, "count": len(undone), "preferences": preferences})
+}
+
+// duplicateSwipe returns a game swiped more than once, which a request can't
+// record: swipes are keyed by request and game so retries don't count twice.
+func duplicateSwipe(swipes []models.SwipeEvent) (int, bool) {
+	seen := make(map[int]bool, len(swipes))
+	for _, swipe := range swipes {
+		if seen[swipe.AppID] {
+			return swipe.AppID, true
+		}
+		seen[swipe.AppID] = true
+	}
+	return 0, false
+}
+
+// tagRequest records the ID of the request on its swipes, taken from the
+// X-Request-ID header so retries are recognized, or generated.
+func tagRequest(c *gin.Context, swipes []models.SwipeEvent) {
+	requestId := c.GetHeader("X-Request-ID")
+	if requestId == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		requestId = hex.EncodeToString(b)
+	}
+	for i := range swipes {
+		swipes[i].RequestID = requestId
+	}
+}
+
+// tagExperiment records the experiment variant the user is in on their swipes.
+func (uh *UserHandler) tagExperiment(id string, swipes []models.SwipeEvent) {
+	assignment, ok := uh.experiments.Assign(id)
+	if !ok {
+		return
+	}
+	for i := range swipes {
+		swipes[i].Experiment = assignment.Experiment
+		swipes[i].Variant = assignment.Variant
+	}
+}
+
+func (uh *UserHandler) Onboard(c *gin.Context) {
+	// Parse id and picks
+	var req struct {
+		ID     string   `json:"id" binding:"required"`
+		Tags   []string `json:"tags"`
+		Genres []string `json:"genres"`
+		AppIDs []int    `json:"appids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(req.Tags) == 0 && len(req.Genres) == 0 && len(req.AppIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pick at least one tag, genre or game"})
 		return
 	}
 
-	preferences, err := uh.ur.GetUserPreference(c.Request.Context(), req.ID)
+	preferences, err := uh.pu.Onboard(c.Request.Context(), req.ID, req.Tags, req.Genres, req.AppIDs)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user preferences %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to onboard user: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// UpdatePlatforms sets the platforms the user plays on, which group
+// recommendations are restricted to.
+func (uh *UserHandler) UpdatePlatforms(c *gin.Context) {
+	// Parse id and platforms
+	var req struct {
+		ID        string   `json:"id" binding:"required"`
+		Platforms []string `json:"platforms" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	for _, platform := range req.Platforms {
+		if !slices.Contains(models.Platforms, platform) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown platform %q", platform)})
+			return
+		}
+	}
 
-	netAddition, err := utils.SumRows(additions)
+	err := uh.ur.UpdatePlatforms(c.Request.Context(), req.ID, req.Platforms)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to sum likes: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update user platforms: %v", err)})
 		return
 	}
-	netSubtraction, err := utils.SumRows(subtractions)
+	c.JSON(http.StatusOK, gin.H{"platforms": req.Platforms})
+}
+
+func (uh *UserHandler) GetSwipes(c *gin.Context) {
+	// Parse id
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	swipes, err := uh.sr.GetByUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to sum dislikes: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user swipes"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"swipes": swipes, "count": len(swipes)})
+}
 
-	preferences, err = utils.AddVectors(preferences, netAddition)
+func (uh *UserHandler) GetInterests(c *gin.Context) {
+	// Parse id
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	interests, err := uh.itr.GetByUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add likes: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user interests"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"interests": interests, "count": len(interests)})
+}
+
+func (uh *UserHandler) GetExclusions(c *gin.Context) {
+	// Parse id
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
 		return
 	}
 
-	preferences, err = utils.SubtractVectors(preferences, netSubtraction)
+	exclusions, err := uh.exr.GetByUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to subtract dislikes: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"exclusions": exclusions})
+}
+
+func (uh *UserHandler) AddExclusions(c *gin.Context) {
+	uh.updateExclusions(c, uh.exr.Add, true)
+}
 
-	preferences = utils.NormalizeVector(preferences)
+func (uh *UserHandler) RemoveExclusions(c *gin.Context) {
+	uh.updateExclusions(c, uh.exr.Remove, false)
+}
 
-	err = uh.ur.UpdateUserPreference(c.Request.Context(), req.ID, preferences)
+// updateExclusions applies update to the exclusions in the request body and
+// responds with the user's resulting list. Genres are trimmed and, when
+// checkGenres is set, must be among the catalog's genre names, whose spelling
+// they take.
+func (uh *UserHandler) updateExclusions(c *gin.Context, update func(context.Context, string, *models.Exclusions) error, checkGenres bool) {
+	// Parse id and exclusions
+	var req struct {
+		ID string `json:"id" binding:"required"`
+		models.Exclusions
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Genres are matched by name, so they must be spelled as the catalog does
+	for i, genre := range req.Genres {
+		req.Genres[i] = strings.TrimSpace(genre)
+	}
+	if checkGenres && len(req.Genres) > 0 {
+		genres, err := uh.gr.GetAllGenres(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get genres"})
+			return
+		}
+		for i, genre := range req.Genres {
+			j := slices.IndexFunc(genres, func(name string) bool { return strings.EqualFold(name, genre) })
+			if j < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown genre %q", genre)})
+				return
+			}
+			req.Genres[i] = genres[j]
+		}
+	}
+
+	if err := update(c.Request.Context(), req.ID, &req.Exclusions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user exclusions"})
+		return
+	}
+	uh.cache.Invalidate(req.ID)
+
+	exclusions, err := uh.exr.GetByUser(c.Request.Context(), req.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update user preferences: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user exclusions"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+	c.JSON(http.StatusOK, gin.H{"exclusions": exclusions})
 }
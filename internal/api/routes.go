@@ -6,10 +6,14 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/ty4g1/gamescout_backend/internal/api/handlers"
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/experiments"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
 	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/services"
 )
 
-func SetupRouter(gr *repository.GameRepository, gmr *repository.GameMediaRepository, ur *repository.UserRepository) *gin.Engine {
+func SetupRouter(cfg *config.Config, gr *repository.GameRepository, gmr *repository.GameMediaRepository, ur *repository.UserRepository, ir *repository.ImpressionRepository, sr *repository.SwipeRepository, itr *repository.InterestRepository, exr *repository.ExclusionRepository, sessions *repository.SessionRepository, cache *services.RecommendationCache, searcher services.Searcher, pipeline *ranking.Pipeline, pu *services.PreferenceUpdater, registry *experiments.Registry, er *repository.ExperimentRepository) *gin.Engine {
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
@@ -19,22 +23,40 @@ func SetupRouter(gr *repository.GameRepository, gmr *repository.GameMediaReposit
 			"http://gamescout.mooo.com:4173", // FreeDNS domain
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	gameHandler := handlers.NewGameHandler(gr, gmr, ur)
-	userHandler := handlers.NewUserHandler(ur, gr)
+	gameHandler := handlers.NewGameHandler(gr, gmr, ur, ir, sr, itr, exr, sessions, cache, searcher, pipeline, registry, cfg)
+	userHandler := handlers.NewUserHandler(ur, gr, sr, itr, exr, pu, cache, registry, cfg)
+	experimentHandler := handlers.NewExperimentHandler(registry, er)
 
 	router.GET("/health", healthCheck)
 	router.GET("/games/random", gameHandler.GetRandomGames)
 	router.GET("/games/recommend", gameHandler.GetRecommendations)
+	router.GET("/games/recommend/group", gameHandler.GetGroupRecommendations)
+	router.POST("/games/recommend/anonymous", gameHandler.GetAnonymousRecommendations)
 	router.GET("/games/tags", gameHandler.GetTags)
 	router.GET("/games/genres", gameHandler.GetGenres)
+	router.GET("/games/index/stats", gameHandler.GetIndexStats)
+	router.GET("/games/cache/stats", gameHandler.GetCacheStats)
+	router.GET("/games/:appid/similar", gameHandler.GetSimilarGames)
 
 	router.POST("/users/add", userHandler.AddUser)
 	router.PATCH("/users/preferences", userHandler.UpdatePreference)
+	router.POST("/users/onboard", userHandler.Onboard)
+	router.PUT("/users/platforms", userHandler.UpdatePlatforms)
+	router.POST("/users/swipes", userHandler.AddSwipes)
+	router.GET("/users/swipes", userHandler.GetSwipes)
+	router.POST("/users/swipes/undo", userHandler.UndoSwipes)
+	router.GET("/users/interests", userHandler.GetInterests)
+	router.GET("/users/exclusions", userHandler.GetExclusions)
+	router.POST("/users/exclusions", userHandler.AddExclusions)
+	router.DELETE("/users/exclusions", userHandler.RemoveExclusions)
+
+	router.GET("/experiments", experimentHandler.GetExperiments)
+	router.GET("/experiments/:name/report", experimentHandler.GetReport)
 
 	return router
 }
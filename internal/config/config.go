@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -15,6 +17,70 @@ type Config struct {
 	SteamSpyURLFormat     string
 	SteamSpyDetailsFormat string
 	SteamAPIDetailsFormat string
+	TagTopN               int
+	TagMinVotes           int
+	TagMaxRepeat          int
+	VectorDim             int
+	HybridVector          bool
+	HybridWeights         HybridWeights
+	SearchBackend         string
+	HNSWEfSearch          int
+	AnnM                  int
+	AnnEfConstruction     int
+	AnnRecallSampleRate   float64
+	RerankPoolFactor      int
+	DefaultDiversity      float64
+	ExploreRate           float64
+	ExploreStrategy       string
+	ExploreSigma          float64
+	RankWeightSimilarity  float64
+	RankWeightQuality     float64
+	RankWeightPopularity  float64
+	ImpressionWindow      time.Duration
+	PreferenceHalfLife    time.Duration
+	InterestClusters      int
+	InterestMaxLikes      int
+	InterestRebuildDelay  time.Duration
+	SwipeWeights          SwipeWeights
+	OnboardSeedWeight     float64
+	OnboardSampleSize     int
+	CFMinSupport          int
+	CFShrinkage           float64
+	CFMaxNeighbors        int
+	CFMaxUserLikes        int
+	CFRebuildInterval     time.Duration
+	RankGenerators        []string
+	RankScorer            string
+	RankModelFile         string
+	RankWeightCF          float64
+	RankWeightFreshness   float64
+	FreshnessHalfLife     time.Duration
+	ExperimentsFile       string
+	RecSessionSize        int
+	RecSessionTTL         time.Duration
+	RecCacheSize          int
+	RecCacheTTL           time.Duration
+}
+
+// HybridWeights scales each block of the hybrid feature vector before it is
+// normalized, controlling how much each block contributes to similarity.
+type HybridWeights struct {
+	Semantic  float64
+	Price     float64
+	Reviews   float64
+	Year      float64
+	Platforms float64
+	Genres    float64
+}
+
+// SwipeWeights is how much each swipe action pulls the user's preference
+// towards the game's vector; negative weights push it away.
+type SwipeWeights struct {
+	Like      float64
+	Dislike   float64
+	Skip      float64
+	Superlike float64
+	Wishlist  float64
 }
 
 func NewConfig() *Config {
@@ -30,6 +96,18 @@ func NewConfig() *Config {
 	steamSpyUrlFormat := os.Getenv("STEAM_SPY_URL_FORMAT")
 	steamSpyDetailsFormat := os.Getenv("STEAM_SPY_DETAILS_FORMAT")
 	steamApiDetailsFormat := os.Getenv("STEAM_API_DETAILS_FORMAT")
+	exploreStrategy := os.Getenv("EXPLORE_STRATEGY")
+	if exploreStrategy == "" {
+		exploreStrategy = "epsilon"
+	}
+	searchBackend := os.Getenv("SEARCH_BACKEND")
+	if searchBackend == "" {
+		searchBackend = "scan"
+	}
+	rankScorer := os.Getenv("RANK_SCORER")
+	if rankScorer == "" {
+		rankScorer = "weighted"
+	}
 	return &Config{
 		ServerAddress:         serverAddr,
 		ApiKey:                apiKey,
@@ -39,5 +117,146 @@ func NewConfig() *Config {
 		SteamSpyURLFormat:     steamSpyUrlFormat,
 		SteamSpyDetailsFormat: steamSpyDetailsFormat,
 		SteamAPIDetailsFormat: steamApiDetailsFormat,
+		TagTopN:               getEnvInt("TAG_TOP_N", 20),
+		TagMinVotes:           getEnvInt("TAG_MIN_VOTES", 0),
+		TagMaxRepeat:          getEnvInt("TAG_MAX_REPEAT", 3),
+		VectorDim:             getEnvInt("VECTOR_DIM", 384),
+		HybridVector:          getEnvBool("HYBRID_VECTOR", false),
+		HybridWeights: HybridWeights{
+			Semantic:  getEnvFloat("HYBRID_WEIGHT_SEMANTIC", 1.0),
+			Price:     getEnvFloat("HYBRID_WEIGHT_PRICE", 0.2),
+			Reviews:   getEnvFloat("HYBRID_WEIGHT_REVIEWS", 0.2),
+			Year:      getEnvFloat("HYBRID_WEIGHT_YEAR", 0.15),
+			Platforms: getEnvFloat("HYBRID_WEIGHT_PLATFORMS", 0.1),
+			Genres:    getEnvFloat("HYBRID_WEIGHT_GENRES", 0.3),
+		},
+		SearchBackend:        searchBackend,
+		HNSWEfSearch:         getEnvInt("HNSW_EF_SEARCH", 100),
+		AnnM:                 getEnvInt("ANN_M", 16),
+		AnnEfConstruction:    getEnvInt("ANN_EF_CONSTRUCTION", 100),
+		AnnRecallSampleRate:  getEnvFloat("ANN_RECALL_SAMPLE_RATE", 0.01),
+		RerankPoolFactor:     getEnvInt("RERANK_POOL_FACTOR", 5),
+		DefaultDiversity:     getEnvFloat("DEFAULT_DIVERSITY", 0),
+		ExploreRate:          getEnvFloat("EXPLORE_RATE", 0.1),
+		ExploreStrategy:      exploreStrategy,
+		ExploreSigma:         getEnvFloat("EXPLORE_SIGMA", 0.1),
+		RankWeightSimilarity: getEnvFloat("RANK_WEIGHT_SIMILARITY", 1.0),
+		RankWeightQuality:    getEnvFloat("RANK_WEIGHT_QUALITY", 0.1),
+		RankWeightPopularity: getEnvFloat("RANK_WEIGHT_POPULARITY", 0.05),
+
+		ImpressionWindow:   getEnvDuration("IMPRESSION_WINDOW", 24*time.Hour),
+		PreferenceHalfLife: getEnvDuration("PREFERENCE_HALF_LIFE", 30*24*time.Hour),
+
+		InterestClusters:     getEnvInt("INTEREST_CLUSTERS", 3),
+		InterestMaxLikes:     getEnvInt("INTEREST_MAX_LIKES", 200),
+		InterestRebuildDelay: getEnvDuration("INTEREST_REBUILD_DELAY", 30*time.Second),
+
+		SwipeWeights: SwipeWeights{
+			Like:      getEnvFloat("SWIPE_WEIGHT_LIKE", 1),
+			Dislike:   getEnvFloat("SWIPE_WEIGHT_DISLIKE", -1),
+			Skip:      getEnvFloat("SWIPE_WEIGHT_SKIP", -0.1),
+			Superlike: getEnvFloat("SWIPE_WEIGHT_SUPERLIKE", 2),
+			Wishlist:  getEnvFloat("SWIPE_WEIGHT_WISHLIST", 1.5),
+		},
+
+		OnboardSeedWeight: getEnvFloat("ONBOARD_SEED_WEIGHT", 2),
+		OnboardSampleSize: getEnvInt("ONBOARD_SAMPLE_SIZE", 200),
+
+		CFMinSupport:      getEnvInt("CF_MIN_SUPPORT", 3),
+		CFShrinkage:       getEnvFloat("CF_SHRINKAGE", 10),
+		CFMaxNeighbors:    getEnvInt("CF_MAX_NEIGHBORS", 50),
+		CFMaxUserLikes:    getEnvInt("CF_MAX_USER_LIKES", 200),
+		CFRebuildInterval: getEnvDuration("CF_REBUILD_INTERVAL", 6*time.Hour),
+
+		RankGenerators:      getEnvList("RANK_GENERATORS", []string{"vector"}),
+		RankScorer:          rankScorer,
+		RankModelFile:       os.Getenv("RANK_MODEL_FILE"),
+		RankWeightCF:        getEnvFloat("RANK_WEIGHT_CF", 0),
+		RankWeightFreshness: getEnvFloat("RANK_WEIGHT_FRESHNESS", 0),
+		FreshnessHalfLife:   getEnvDuration("FRESHNESS_HALF_LIFE", 365*24*time.Hour),
+
+		ExperimentsFile: os.Getenv("EXPERIMENTS_FILE"),
+
+		RecSessionSize: getEnvInt("REC_SESSION_SIZE", 100),
+		RecSessionTTL:  getEnvDuration("REC_SESSION_TTL", 30*time.Minute),
+
+		RecCacheSize: getEnvInt("REC_CACHE_SIZE", 1000),
+		RecCacheTTL:  getEnvDuration("REC_CACHE_TTL", 10*time.Minute),
+	}
+}
+
+// getEnvInt reads an integer from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Error parsing %s from env, defaulting to %d: %v\n", key, def, err)
+		return def
+	}
+	return val
+}
+
+// getEnvFloat reads a float from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvFloat(key string, def float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("Error parsing %s from env, defaulting to %v: %v\n", key, def, err)
+		return def
+	}
+	return val
+}
+
+// getEnvBool reads a boolean from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvBool(key string, def bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("Error parsing %s from env, defaulting to %v: %v\n", key, def, err)
+		return def
+	}
+	return val
+}
+
+// getEnvDuration reads a duration such as "24h" from the environment, falling
+// back to def when the variable is unset or malformed.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	val, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("Error parsing %s from env, defaulting to %v: %v\n", key, def, err)
+		return def
+	}
+	return val
+}
+
+// getEnvList reads a comma separated list from the environment, falling back
+// to def when the variable is unset.
+func getEnvList(key string, def []string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	var vals []string
+	for _, val := range strings.Split(raw, ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			vals = append(vals, val)
+		}
 	}
+	return vals
 }
@@ -0,0 +1,136 @@
+// Package experiments assigns users to variants of A/B experiments on the
+// recommendation strategy.
+package experiments
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+// Variant is one arm of an experiment. Params are recommendation query
+// parameters, such as "mode" or "w_quality", applied to its users' requests.
+type Variant struct {
+	Name   string            `json:"name"`
+	Weight float64           `json:"weight"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+type Experiment struct {
+	Name     string    `json:"name"`
+	Active   bool      `json:"active"`
+	Variants []Variant `json:"variants"`
+}
+
+// Assignment is the variant a user was put in.
+type Assignment struct {
+	Experiment string            `json:"experiment"`
+	Variant    string            `json:"variant"`
+	Params     map[string]string `json:"-"`
+}
+
+// Registry holds the configured experiments. Only the first active one runs,
+// so users are never in two experiments at once.
+type Registry struct {
+	experiments []Experiment
+}
+
+// Load reads experiments from a JSON file holding a list of experiments. An
+// empty path gives a registry without experiments.
+func Load(path string) (*Registry, error) {
+	if path == "" {
+		return &Registry{}, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var experiments []Experiment
+	if err := json.Unmarshal(raw, &experiments); err != nil {
+		return nil, fmt.Errorf("failed to parse experiments file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(experiments))
+	for _, e := range experiments {
+		if names[e.Name] {
+			return nil, fmt.Errorf("duplicate experiment %q", e.Name)
+		}
+		names[e.Name] = true
+
+		total := 0.0
+		for _, v := range e.Variants {
+			if v.Weight < 0 {
+				return nil, fmt.Errorf("experiment %q: variant %q has a negative weight", e.Name, v.Name)
+			}
+			total += v.Weight
+		}
+		if total <= 0 {
+			return nil, fmt.Errorf("experiment %q has no traffic", e.Name)
+		}
+	}
+
+	return &Registry{experiments: experiments}, nil
+}
+
+// Experiments returns every configured experiment.
+func (r *Registry) Experiments() []Experiment {
+	return r.experiments
+}
+
+// Get returns the experiment with the given name.
+func (r *Registry) Get(name string) (Experiment, bool) {
+	for _, e := range r.experiments {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Experiment{}, false
+}
+
+// Assign returns the user's variant of the running experiment, if any.
+func (r *Registry) Assign(userID string) (Assignment, bool) {
+	for _, e := range r.experiments {
+		if e.Active {
+			v := e.Assign(userID)
+			return Assignment{Experiment: e.Name, Variant: v.Name, Params: v.Params}, true
+		}
+	}
+	return Assignment{}, false
+}
+
+// Assign picks the user's variant by hashing their id with the experiment
+// name, so a user always lands in the same variant and assignments are
+// independent between experiments.
+func (e *Experiment) Assign(userID string) Variant {
+	h := fnv.New64a()
+	h.Write([]byte(e.Name + ":" + userID))
+	// Top 53 bits give a uniform float in [0, 1)
+	point := float64(mix(h.Sum64())>>11) / (1 << 53)
+
+	total := 0.0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+
+	point *= total
+	for _, v := range e.Variants {
+		if point < v.Weight {
+			return v
+		}
+		point -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// mix spreads every input bit over the whole hash, which FNV alone does
+// poorly for ids that differ only in their last characters.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,18 @@
+package models
+
+type ExclusionKind string
+
+const (
+	ExcludeTag       ExclusionKind = "tag"
+	ExcludeGenre     ExclusionKind = "genre"
+	ExcludeDeveloper ExclusionKind = "developer"
+	ExcludeGame      ExclusionKind = "game"
+)
+
+// Exclusions are what a user never wants to be shown.
+type Exclusions struct {
+	Tags       []string `json:"tags"`
+	Genres     []string `json:"genres"`
+	Developers []string `json:"developers"`
+	AppIDs     []int    `json:"appids"`
+}
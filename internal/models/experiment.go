@@ -0,0 +1,11 @@
+package models
+
+// VariantReport sums up how users in one variant of an experiment engaged
+// with their recommendations.
+type VariantReport struct {
+	Variant     string  `json:"variant"`
+	Impressions int     `json:"impressions"`
+	Swipes      int     `json:"swipes"`
+	Likes       int     `json:"likes"`
+	LikeRate    float64 `json:"like_rate"`
+}
@@ -11,3 +11,95 @@ type ReleaseDate struct {
 	IsBefore bool
 	Date     time.Time
 }
+
+// GameFilter restricts which games are returned. Genres are matched by their
+// names as returned by GenreNames, such as "Free to Play".
+type GameFilter struct {
+	PriceRange  *PriceRange
+	ReleaseDate *ReleaseDate
+	Tags        []string
+	Genres      []string
+	Platforms   []string
+	Exclude     []int
+	// Drop the DLC of these games
+	ExcludeDLCOf []int
+	// Drop games with any of these tags, genres or developers
+	ExcludeTags       []string
+	ExcludeGenres     []string
+	ExcludeDevelopers []string
+}
+
+// ApplyExclusions adds a user's exclusions on top of the filter.
+func (f *GameFilter) ApplyExclusions(exclusions *Exclusions) {
+	f.Exclude = append(f.Exclude, exclusions.AppIDs...)
+	f.ExcludeTags = append(f.ExcludeTags, exclusions.Tags...)
+	f.ExcludeGenres = append(f.ExcludeGenres, exclusions.Genres...)
+	f.ExcludeDevelopers = append(f.ExcludeDevelopers, exclusions.Developers...)
+}
+
+// Matcher returns a predicate reporting whether a game passes the filter,
+// mirroring the SQL conditions applied by the repository.
+func (f *GameFilter) Matcher() func(*Game) bool {
+	exclude := make(map[int]bool, len(f.Exclude))
+	for _, appId := range f.Exclude {
+		exclude[appId] = true
+	}
+
+	excludeDLCOf := make(map[int]bool, len(f.ExcludeDLCOf))
+	for _, appId := range f.ExcludeDLCOf {
+		excludeDLCOf[appId] = true
+	}
+
+	return func(g *Game) bool {
+		if exclude[g.AppId] {
+			return false
+		}
+		if g.ParentAppId != 0 && excludeDLCOf[g.ParentAppId] {
+			return false
+		}
+		if f.PriceRange != nil && (g.Price < f.PriceRange.Min || g.Price > f.PriceRange.Max) {
+			return false
+		}
+		if f.ReleaseDate != nil {
+			if f.ReleaseDate.IsBefore && !g.ReleaseDate.Before(f.ReleaseDate.Date) {
+				return false
+			}
+			if !f.ReleaseDate.IsBefore && !g.ReleaseDate.After(f.ReleaseDate.Date) {
+				return false
+			}
+		}
+		if f.Tags != nil && !hasAnyTag(g.Tags, f.Tags) {
+			return false
+		}
+		if f.Genres != nil && !overlaps(GenreNames(g.Genres), f.Genres) {
+			return false
+		}
+		if f.Platforms != nil && !overlaps(g.Platforms, f.Platforms) {
+			return false
+		}
+		if hasAnyTag(g.Tags, f.ExcludeTags) || overlaps(GenreNames(g.Genres), f.ExcludeGenres) || overlaps(g.Developers, f.ExcludeDevelopers) {
+			return false
+		}
+		return true
+	}
+}
+
+func hasAnyTag(tags map[string]int, want []string) bool {
+	for _, tag := range want {
+		if _, ok := tags[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
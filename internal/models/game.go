@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Game struct {
 	AppId         int
@@ -15,5 +18,28 @@ type Game struct {
 	Positive      int
 	Negative      int
 	Platforms     []string
+	Developers    []string
 	FeatureVector []float64
+	DLC           []int
+	// Appid of the base game when this game is a DLC, 0 otherwise
+	ParentAppId int
+}
+
+type ScoredGame struct {
+	Game
+	Score float64
+	// Components of Score by name, filled in by ranking stages
+	Breakdown map[string]float64
+}
+
+// GenreNames recovers the SteamSpy genre names from Game.Genres, which holds
+// the comma separated genre string split on spaces.
+func GenreNames(genres []string) []string {
+	var names []string
+	for _, name := range strings.Split(strings.Join(genres, " "), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
 }
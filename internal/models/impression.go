@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Impression struct {
+	UserID  string
+	AppID   int
+	Source  string
+	Slot    string
+	ShownAt time.Time
+	// Experiment and variant the user was in, if any
+	Experiment string
+	Variant    string
+}
@@ -0,0 +1,9 @@
+package models
+
+type Interest struct {
+	UserID   string    `json:"-"`
+	Position int       `json:"position"`
+	Centroid []float64 `json:"-"`
+	Size     int       `json:"size"`
+	Tags     []string  `json:"tags"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RecSession is a ranked list of recommendations computed once and served
+// page by page until it expires.
+type RecSession struct {
+	ID     string
+	UserID string
+	Items  []SessionItem
+	// Experiment and variant the list was ranked for, if any
+	Experiment string
+	Variant    string
+	ExpiresAt  time.Time
+}
+
+type SessionItem struct {
+	AppID int                `json:"appid"`
+	Slot  string             `json:"slot,omitempty"`
+	Score map[string]float64 `json:"score,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// ItemSimilarity is how similar two games are judged by the users who liked
+// both of them.
+type ItemSimilarity struct {
+	AppID      int
+	Neighbor   int
+	Similarity float64
+	// Number of users who liked both games
+	Support int
+}
@@ -35,4 +35,9 @@ type SteamAPIDetails struct {
 	Background  string          `json:"background"`
 	Screenshots []Screenshot    `json:"screenshots"`
 	Movies      []Movie         `json:"movies"`
+	DLC         []int           `json:"dlc"`
+	Developers  []string        `json:"developers"`
+	FullGame    struct {
+		AppID string `json:"appid"`
+	} `json:"fullgame"`
 }
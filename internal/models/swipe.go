@@ -0,0 +1,46 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type SwipeAction string
+
+const (
+	SwipeLike      SwipeAction = "like"
+	SwipeDislike   SwipeAction = "dislike"
+	SwipeSkip      SwipeAction = "skip"
+	SwipeSuperlike SwipeAction = "superlike"
+	SwipeWishlist  SwipeAction = "wishlist"
+)
+
+// LikedActions are the swipe actions that express interest in a game.
+var LikedActions = []SwipeAction{SwipeLike, SwipeSuperlike, SwipeWishlist}
+
+// Valid reports whether a is one of the known swipe actions.
+func (a SwipeAction) Valid() bool {
+	switch a {
+	case SwipeLike, SwipeDislike, SwipeSkip, SwipeSuperlike, SwipeWishlist:
+		return true
+	}
+	return false
+}
+
+// Liked reports whether a expresses interest in the game, which makes the
+// game count as liked for interests and explanations.
+func (a SwipeAction) Liked() bool {
+	return slices.Contains(LikedActions, a)
+}
+
+type SwipeEvent struct {
+	UserID    string      `json:"-"`
+	AppID     int         `json:"appid"`
+	Action    SwipeAction `json:"action"`
+	CreatedAt time.Time   `json:"created_at"`
+	// Experiment and variant the user was in, if any
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	// ID of the API request that recorded the swipe
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -1,5 +1,8 @@
 package models
 
+// Platforms are the platforms Steam lists games for.
+var Platforms = []string{"windows", "mac", "linux"}
+
 type User struct {
 	ID               string
 	SwipeHistory     []int
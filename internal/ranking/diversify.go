@@ -0,0 +1,80 @@
+package ranking
+
+import (
+	"math"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// Diversify picks limit games from candidates, which must be sorted best
+// first, using Maximal Marginal Relevance. Each pick maximizes
+//
+//	(1 - diversity) * score - diversity * max similarity to the games already picked
+//
+// so diversity 0 keeps the original order and 1 favours variety only. When
+// maxPerTag is positive, at most that many picks may share the same top tag;
+// if the quota leaves slots empty they are filled in MMR order regardless.
+func Diversify(candidates []models.ScoredGame, limit int, diversity float64, maxPerTag int) []models.ScoredGame {
+	if diversity <= 0 && maxPerTag <= 0 {
+		return candidates[:min(limit, len(candidates))]
+	}
+
+	picked := make([]models.ScoredGame, 0, limit)
+	used := make([]bool, len(candidates))
+	// Highest similarity of each candidate to any picked game
+	redundancy := make([]float64, len(candidates))
+	tagCounts := make(map[string]int)
+
+	for _, enforceQuota := range []bool{maxPerTag > 0, false} {
+		for len(picked) < limit {
+			best := -1
+			bestScore := math.Inf(-1)
+			for i, c := range candidates {
+				if used[i] {
+					continue
+				}
+				if enforceQuota && tagCounts[TopTag(c.Tags)] >= maxPerTag {
+					continue
+				}
+				mmr := (1-diversity)*c.Score - diversity*redundancy[i]
+				if mmr > bestScore {
+					best, bestScore = i, mmr
+				}
+			}
+			if best < 0 {
+				break
+			}
+
+			used[best] = true
+			picked = append(picked, candidates[best])
+			tagCounts[TopTag(candidates[best].Tags)]++
+
+			for i, c := range candidates {
+				if used[i] {
+					continue
+				}
+				sim, err := utils.ComputeSimilarity(c.FeatureVector, candidates[best].FeatureVector)
+				if err != nil {
+					sim = 0
+				}
+				if len(picked) == 1 || sim > redundancy[i] {
+					redundancy[i] = sim
+				}
+			}
+		}
+	}
+
+	return picked
+}
+
+// TopTag returns the tag with the most votes, breaking ties alphabetically.
+func TopTag(tags map[string]int) string {
+	top := ""
+	for tag, votes := range tags {
+		if top == "" || votes > tags[top] || (votes == tags[top] && tag < top) {
+			top = tag
+		}
+	}
+	return top
+}
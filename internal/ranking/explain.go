@@ -0,0 +1,96 @@
+package ranking
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+const (
+	// How many of each game's top tags are compared
+	explainTopTags = 10
+	// How many shared tags an explanation lists
+	explainMaxTags = 5
+)
+
+// Explanation says why a game was recommended in terms of the games the user
+// liked.
+type Explanation struct {
+	Tags            []string    `json:"tags,omitempty"`
+	Genres          []string    `json:"genres,omitempty"`
+	BecauseYouLiked []LikedGame `json:"because_you_liked,omitempty"`
+}
+
+// LikedGame is a liked game close to the recommended one.
+type LikedGame struct {
+	AppId      int     `json:"appid"`
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Explain returns the top tags and the genres game shares with the liked
+// games, the tags ordered by how many liked games share them, along with the
+// nearest liked games by feature vector similarity.
+func Explain(game models.Game, liked []models.Game, nearest int) Explanation {
+	tagCounts := make(map[string]int)
+	genres := make(map[string]bool)
+	for _, l := range liked {
+		for _, tag := range topTags(l.Tags, explainTopTags) {
+			tagCounts[tag]++
+		}
+		for _, genre := range models.GenreNames(l.Genres) {
+			genres[genre] = true
+		}
+	}
+
+	var explanation Explanation
+	for _, tag := range topTags(game.Tags, explainTopTags) {
+		if tagCounts[tag] > 0 {
+			explanation.Tags = append(explanation.Tags, tag)
+		}
+	}
+	sort.SliceStable(explanation.Tags, func(i, j int) bool {
+		return tagCounts[explanation.Tags[i]] > tagCounts[explanation.Tags[j]]
+	})
+	explanation.Tags = explanation.Tags[:min(explainMaxTags, len(explanation.Tags))]
+
+	for _, genre := range models.GenreNames(game.Genres) {
+		if genres[genre] && !slices.Contains(explanation.Genres, genre) {
+			explanation.Genres = append(explanation.Genres, genre)
+		}
+	}
+
+	for _, l := range liked {
+		if l.AppId == game.AppId {
+			continue
+		}
+		similarity, err := utils.ComputeSimilarity(game.FeatureVector, l.FeatureVector)
+		if err != nil || similarity <= 0 {
+			continue
+		}
+		explanation.BecauseYouLiked = append(explanation.BecauseYouLiked, LikedGame{AppId: l.AppId, Name: l.Name, Similarity: similarity})
+	}
+	sort.SliceStable(explanation.BecauseYouLiked, func(i, j int) bool {
+		return explanation.BecauseYouLiked[i].Similarity > explanation.BecauseYouLiked[j].Similarity
+	})
+	explanation.BecauseYouLiked = explanation.BecauseYouLiked[:min(nearest, len(explanation.BecauseYouLiked))]
+
+	return explanation
+}
+
+// topTags returns up to n tags with the most votes, ties broken by name.
+func topTags(tags map[string]int, n int) []string {
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if tags[names[i]] != tags[names[j]] {
+			return tags[names[i]] > tags[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names[:min(n, len(names))]
+}
@@ -0,0 +1,80 @@
+package ranking
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+const (
+	SlotExploit = "exploit"
+	SlotExplore = "explore"
+)
+
+// DeckItem is a game in a recommendation deck, labeled with whether it fills
+// an exploit slot (ranked by preference) or an explore slot.
+type DeckItem struct {
+	models.ScoredGame
+	Slot string
+}
+
+// ExploreSlots draws how many of limit slots are explore slots, each slot
+// exploring independently with probability rate.
+func ExploreSlots(limit int, rate float64, rng *rand.Rand) int {
+	n := 0
+	for range limit {
+		if rng.Float64() < rate {
+			n++
+		}
+	}
+	return n
+}
+
+// ThompsonPick picks n games from candidates by Thompson sampling: each
+// game's score is treated as the mean of a normal distribution whose spread
+// shrinks as its review count grows, and the n highest samples win. Games
+// the catalog knows little about therefore get picked more often than their
+// score alone would allow.
+func ThompsonPick(candidates []models.ScoredGame, n int, sigma float64, rng *rand.Rand) []models.ScoredGame {
+	type sample struct {
+		game  models.ScoredGame
+		value float64
+	}
+
+	samples := make([]sample, 0, len(candidates))
+	for _, c := range candidates {
+		spread := sigma / math.Sqrt(1+math.Log1p(float64(c.Positive+c.Negative)))
+		samples = append(samples, sample{game: c, value: c.Score + rng.NormFloat64()*spread})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value > samples[j].value })
+
+	picked := make([]models.ScoredGame, 0, n)
+	for _, s := range samples[:min(n, len(samples))] {
+		picked = append(picked, s.game)
+	}
+	return picked
+}
+
+// Mix interleaves explore games into random positions of the exploit games,
+// which keep their relative order.
+func Mix(exploit []models.ScoredGame, explore []models.ScoredGame, rng *rand.Rand) []DeckItem {
+	total := len(exploit) + len(explore)
+	isExplore := make([]bool, total)
+	for _, pos := range rng.Perm(total)[:len(explore)] {
+		isExplore[pos] = true
+	}
+
+	deck := make([]DeckItem, 0, total)
+	for _, explored := range isExplore {
+		if explored {
+			deck = append(deck, DeckItem{ScoredGame: explore[0], Slot: SlotExplore})
+			explore = explore[1:]
+		} else {
+			deck = append(deck, DeckItem{ScoredGame: exploit[0], Slot: SlotExploit})
+			exploit = exploit[1:]
+		}
+	}
+	return deck
+}
@@ -0,0 +1,80 @@
+package ranking
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// SimilarityExtractor is the best cosine similarity between a candidate and
+// any of the request's vectors.
+type SimilarityExtractor struct{}
+
+func (SimilarityExtractor) Name() string { return "similarity" }
+
+func (SimilarityExtractor) Extract(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]float64, error) {
+	values := make([]float64, len(candidates))
+	utils.ParallelFor(len(candidates), func(i int) {
+		best := math.Inf(-1)
+		for _, vector := range req.Vectors {
+			if similarity, err := utils.ComputeSimilarity(vector, candidates[i].FeatureVector); err == nil {
+				best = max(best, similarity)
+			}
+		}
+		if !math.IsInf(best, -1) {
+			values[i] = best
+		}
+	})
+	return values, nil
+}
+
+// QualityExtractor is the Wilson lower bound of a candidate's review score.
+type QualityExtractor struct{}
+
+func (QualityExtractor) Name() string { return "quality" }
+
+func (QualityExtractor) Extract(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]float64, error) {
+	values := make([]float64, len(candidates))
+	for i, c := range candidates {
+		values[i] = WilsonLowerBound(c.Positive, c.Negative)
+	}
+	return values, nil
+}
+
+// PopularityExtractor is a candidate's popularity prior.
+type PopularityExtractor struct{}
+
+func (PopularityExtractor) Name() string { return "popularity" }
+
+func (PopularityExtractor) Extract(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]float64, error) {
+	values := make([]float64, len(candidates))
+	for i, c := range candidates {
+		values[i] = Popularity(c.Positive, c.Negative)
+	}
+	return values, nil
+}
+
+// FreshnessExtractor halves with every HalfLife since a candidate's release,
+// starting from 1 for games released today or later.
+type FreshnessExtractor struct {
+	HalfLife time.Duration
+}
+
+func (FreshnessExtractor) Name() string { return "freshness" }
+
+func (fe FreshnessExtractor) Extract(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]float64, error) {
+	now := time.Now()
+	values := make([]float64, len(candidates))
+	for i, c := range candidates {
+		age := now.Sub(c.ReleaseDate)
+		if fe.HalfLife <= 0 || age <= 0 {
+			values[i] = 1
+			continue
+		}
+		values[i] = math.Exp2(-float64(age) / float64(fe.HalfLife))
+	}
+	return values, nil
+}
@@ -0,0 +1,89 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+func TestSimilarityExtractor(t *testing.T) {
+	req := &Request{Vectors: [][]float64{{1, 0, 0}, {0, 1, 0}}}
+	// Enough candidates to be split across several workers
+	candidates := make([]models.ScoredGame, 10_000)
+	for i := range candidates {
+		switch i % 3 {
+		case 0:
+			candidates[i].FeatureVector = []float64{0.6, 0.8, 0}
+		case 1:
+			candidates[i].FeatureVector = []float64{-0.6, -0.8, 0}
+		default:
+			// Not comparable with any of the vectors
+			candidates[i].FeatureVector = []float64{1, 0}
+		}
+	}
+
+	values, err := SimilarityExtractor{}.Extract(context.Background(), req, candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{0.8, -0.6, 0}
+	for i, value := range values {
+		if diff := value - want[i%3]; diff > 1e-9 || diff < -1e-9 {
+			t.Fatalf("candidate %d has similarity %f, want %f", i, value, want[i%3])
+		}
+	}
+}
+
+// BenchmarkPipelineScore scores synthetic candidate pools with the default
+// features against a preference and two interests, as the recommendation
+// endpoint does after retrieval.
+func BenchmarkPipelineScore(b *testing.B) {
+	const dim = 32
+	rng := rand.New(rand.NewSource(1))
+	randomVector := func() []float64 {
+		v := make([]float64, dim)
+		for i := range v {
+			v[i] = rng.NormFloat64()
+		}
+		return utils.NormalizeVector(v)
+	}
+
+	pipeline := &Pipeline{
+		Extractors: []Extractor{
+			SimilarityExtractor{},
+			QualityExtractor{},
+			PopularityExtractor{},
+			FreshnessExtractor{HalfLife: 365 * 24 * time.Hour},
+		},
+		Scorer: WeightedScorer{"similarity": 1, "quality": 0.2, "popularity": 0.1, "freshness": 0.1},
+	}
+	req := &Request{Vectors: [][]float64{randomVector(), randomVector(), randomVector()}}
+
+	for _, n := range []int{10_000, 100_000} {
+		pool := make([]models.ScoredGame, n)
+		for i := range pool {
+			pool[i].AppId = i
+			pool[i].FeatureVector = randomVector()
+			pool[i].Positive = rng.Intn(10_000)
+			pool[i].Negative = rng.Intn(1_000)
+			pool[i].ReleaseDate = time.Now().AddDate(0, 0, -rng.Intn(3650))
+		}
+		candidates := make([]models.ScoredGame, n)
+
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				copy(candidates, pool)
+				if _, err := pipeline.Score(context.Background(), req, candidates); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
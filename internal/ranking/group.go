@@ -0,0 +1,68 @@
+package ranking
+
+import (
+	"math"
+	"sort"
+)
+
+// GroupStrategy is how the members' scores for a game combine into the
+// group's score.
+type GroupStrategy string
+
+const (
+	// Mean of the members' scores, which for cosine similarity is the
+	// similarity to the average of their preference vectors
+	GroupAverage GroupStrategy = "average"
+	// Lowest of the members' scores, so nobody is stuck with a game they'd hate
+	GroupLeastMisery GroupStrategy = "least_misery"
+	// Sum of the points each member's ranking gives a game: n-1 for their
+	// favourite of n candidates down to 0 for their least favourite
+	GroupBorda GroupStrategy = "borda"
+)
+
+// Valid reports whether s is one of the known strategies.
+func (s GroupStrategy) Valid() bool {
+	switch s {
+	case GroupAverage, GroupLeastMisery, GroupBorda:
+		return true
+	}
+	return false
+}
+
+// AggregateGroup returns the group score of every candidate, where
+// scores[m][i] is member m's score for candidate i.
+func AggregateGroup(strategy GroupStrategy, scores [][]float64) []float64 {
+	if len(scores) == 0 {
+		return nil
+	}
+	n := len(scores[0])
+	group := make([]float64, n)
+
+	switch strategy {
+	case GroupLeastMisery:
+		for i := range group {
+			group[i] = math.Inf(1)
+			for _, member := range scores {
+				group[i] = min(group[i], member[i])
+			}
+		}
+	case GroupBorda:
+		order := make([]int, n)
+		for _, member := range scores {
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(a, b int) bool { return member[order[a]] > member[order[b]] })
+			for rank, i := range order {
+				group[i] += float64(n - 1 - rank)
+			}
+		}
+	default:
+		for _, member := range scores {
+			for i, score := range member {
+				group[i] += score / float64(len(scores))
+			}
+		}
+	}
+	return group
+}
@@ -0,0 +1,32 @@
+package ranking
+
+import "github.com/ty4g1/gamescout_backend/internal/models"
+
+// Interleave merges lists, each sorted best first, by taking one game from
+// each list in turn. Games already taken from an earlier list are skipped.
+func Interleave(lists [][]models.ScoredGame) []models.ScoredGame {
+	total := 0
+	for _, list := range lists {
+		total += len(list)
+	}
+
+	merged := make([]models.ScoredGame, 0, total)
+	taken := make(map[int]bool, total)
+	for i := 0; len(merged) < total; i++ {
+		progressed := false
+		for _, list := range lists {
+			if i >= len(list) {
+				continue
+			}
+			progressed = true
+			if game := list[i]; !taken[game.AppId] {
+				taken[game.AppId] = true
+				merged = append(merged, game)
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	return merged
+}
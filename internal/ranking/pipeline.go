@@ -0,0 +1,240 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+// Request describes who a pipeline ranks games for.
+type Request struct {
+	UserID string
+	// Vectors candidates are compared against: the user's preference vector
+	// and their interest centroids steered by it, or just the preference
+	Vectors  [][]float64
+	Filter   *models.GameFilter
+	PoolSize int
+	// Names of the generators to draw candidates from; nil uses the
+	// pipeline's defaults
+	Generators []string
+	// Overrides of the weighted scorer's feature weights
+	Weights map[string]float64
+}
+
+// Generator proposes candidate games for a request. It may return several
+// channels, such as one per interest, each sorted best first.
+type Generator interface {
+	Name() string
+	Generate(ctx context.Context, req *Request) ([][]models.ScoredGame, error)
+}
+
+// Extractor computes one named feature for every candidate.
+type Extractor interface {
+	Name() string
+	Extract(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]float64, error)
+}
+
+// Scorer combines a candidate's features into its final score.
+type Scorer interface {
+	Score(features map[string]float64) float64
+}
+
+// Pipeline ranks games in three stages: generators propose candidates,
+// extractors compute their features and the scorer turns the features into a
+// final score. Channels are sorted by final score and interleaved, so no
+// single generator or interest crowds out the others.
+type Pipeline struct {
+	Generators []Generator
+	// Names of the generators used when a request doesn't pick any
+	Defaults []string
+	// Fallback tops the pool up when the generators come up short
+	Fallback   Generator
+	Extractors []Extractor
+	Scorer     Scorer
+}
+
+// Rank returns up to req.PoolSize scored candidates, best first.
+func (p *Pipeline) Rank(ctx context.Context, req *Request) ([]models.ScoredGame, error) {
+	names := req.Generators
+	if names == nil {
+		names = p.Defaults
+	}
+
+	var channels [][]models.ScoredGame
+	used := make(map[string]bool)
+	for _, g := range p.Generators {
+		if !slices.Contains(names, g.Name()) {
+			continue
+		}
+		lists, err := g.Generate(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s generator: %w", g.Name(), err)
+		}
+		channels = append(channels, lists...)
+		used[g.Name()] = true
+	}
+
+	var fallback [][]models.ScoredGame
+	if p.Fallback != nil && !used[p.Fallback.Name()] && len(Interleave(channels)) < req.PoolSize {
+		lists, err := p.Fallback.Generate(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s generator: %w", p.Fallback.Name(), err)
+		}
+		fallback = lists
+	}
+
+	// Score every distinct candidate once, then sort each channel by it
+	all := Interleave(append(slices.Clone(channels), fallback...))
+	all, err := p.Score(ctx, req, all)
+	if err != nil {
+		return nil, err
+	}
+	scored := make(map[int]models.ScoredGame, len(all))
+	for _, c := range all {
+		scored[c.AppId] = c
+	}
+	rescore := func(lists [][]models.ScoredGame) [][]models.ScoredGame {
+		for i, list := range lists {
+			out := make([]models.ScoredGame, 0, len(list))
+			for _, c := range list {
+				out = append(out, scored[c.AppId])
+			}
+			sortByScore(out)
+			lists[i] = out
+		}
+		return lists
+	}
+
+	ranked := Interleave(rescore(channels))
+	if len(fallback) > 0 {
+		// Keep the fallback behind what the generators proposed
+		present := make(map[int]bool, len(ranked))
+		for _, c := range ranked {
+			present[c.AppId] = true
+		}
+		for _, c := range Interleave(rescore(fallback)) {
+			if !present[c.AppId] {
+				ranked = append(ranked, c)
+			}
+		}
+	}
+	return ranked[:min(req.PoolSize, len(ranked))], nil
+}
+
+// Score extracts the features of candidates the scorer uses, records them in
+// Breakdown along with the final score and returns the candidates best first.
+// Features a weighted scorer gives no weight are skipped.
+func (p *Pipeline) Score(ctx context.Context, req *Request, candidates []models.ScoredGame) ([]models.ScoredGame, error) {
+	for i := range candidates {
+		candidates[i].Breakdown = make(map[string]float64, len(p.Extractors)+1)
+	}
+
+	scorer := p.scorer(req)
+	ws, weighted := scorer.(WeightedScorer)
+	for _, e := range p.Extractors {
+		if weighted && ws[e.Name()] == 0 {
+			continue
+		}
+		values, err := e.Extract(ctx, req, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("%s feature: %w", e.Name(), err)
+		}
+		for i, value := range values {
+			candidates[i].Breakdown[e.Name()] = value
+		}
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		c.Score = scorer.Score(c.Breakdown)
+		c.Breakdown["final"] = c.Score
+	}
+
+	sortByScore(candidates)
+	return candidates, nil
+}
+
+// Reranks reports whether the final order can differ from similarity order,
+// in which case callers should ask for a larger pool.
+func (p *Pipeline) Reranks(req *Request) bool {
+	ws, ok := p.scorer(req).(WeightedScorer)
+	if !ok {
+		return true
+	}
+	for feature, weight := range ws {
+		if feature != "similarity" && weight != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pipeline) scorer(req *Request) Scorer {
+	if ws, ok := p.Scorer.(WeightedScorer); ok && len(req.Weights) > 0 {
+		return ws.With(req.Weights)
+	}
+	return p.Scorer
+}
+
+func sortByScore(candidates []models.ScoredGame) {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+}
+
+// WeightedScorer scores candidates by a weighted sum of their features.
+type WeightedScorer map[string]float64
+
+func (ws WeightedScorer) Score(features map[string]float64) float64 {
+	var score float64
+	for feature, weight := range ws {
+		score += weight * features[feature]
+	}
+	return score
+}
+
+// With returns a copy of the scorer with some weights replaced.
+func (ws WeightedScorer) With(overrides map[string]float64) WeightedScorer {
+	merged := make(WeightedScorer, len(ws)+len(overrides))
+	for feature, weight := range ws {
+		merged[feature] = weight
+	}
+	for feature, weight := range overrides {
+		merged[feature] = weight
+	}
+	return merged
+}
+
+// LogisticScorer scores candidates by the probability a logistic regression
+// model, trained offline on swipes, gives to them being liked.
+type LogisticScorer struct {
+	Bias    float64            `json:"bias"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// LoadLogisticScorer reads a model saved as JSON, such as
+//
+//	{"bias": -1.2, "weights": {"similarity": 3.1, "quality": 0.8}}
+func LoadLogisticScorer(path string) (*LogisticScorer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scorer LogisticScorer
+	if err := json.Unmarshal(raw, &scorer); err != nil {
+		return nil, fmt.Errorf("failed to parse ranking model %s: %w", path, err)
+	}
+	return &scorer, nil
+}
+
+func (ls *LogisticScorer) Score(features map[string]float64) float64 {
+	z := ls.Bias
+	for feature, weight := range ls.Weights {
+		z += weight * features[feature]
+	}
+	return 1 / (1 + math.Exp(-z))
+}
@@ -0,0 +1,27 @@
+package ranking
+
+import "math"
+
+// z-score of the 95% confidence interval used for the Wilson bound
+const wilsonZ = 1.96
+
+// Review counts at or above this get the full popularity prior.
+const popularitySaturation = 1_000_000
+
+// WilsonLowerBound returns the lower bound of the Wilson score interval for
+// the share of positive reviews, so a handful of reviews can't outrank a
+// large, consistently positive sample. Games without reviews score 0.
+func WilsonLowerBound(positive int, negative int) float64 {
+	n := float64(positive + negative)
+	if n == 0 {
+		return 0
+	}
+	p := float64(positive) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// Popularity returns the log review count scaled to [0, 1].
+func Popularity(positive int, negative int) float64 {
+	return math.Min(math.Log1p(float64(positive+negative))/math.Log1p(popularitySaturation), 1)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type ExclusionRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewExclusionRepository(pool *pgxpool.Pool) *ExclusionRepository {
+	return &ExclusionRepository{
+		Pool: pool,
+	}
+}
+
+// Add adds the exclusions to the user's list, ignoring ones already on it.
+func (er *ExclusionRepository) Add(ctx context.Context, id string, exclusions *models.Exclusions) error {
+	return er.exec(ctx, id, exclusions, `
+		INSERT INTO user_exclusions (cookie_id, kind, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`)
+}
+
+// Remove removes the exclusions from the user's list.
+func (er *ExclusionRepository) Remove(ctx context.Context, id string, exclusions *models.Exclusions) error {
+	return er.exec(ctx, id, exclusions, `
+		DELETE FROM user_exclusions
+		WHERE cookie_id = $1 AND kind = $2 AND value = $3
+	`)
+}
+
+func (er *ExclusionRepository) GetByUser(ctx context.Context, id string) (*models.Exclusions, error) {
+	conn, err := er.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT kind, value FROM user_exclusions
+		WHERE cookie_id = $1
+		ORDER BY created_at, value
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exclusions := &models.Exclusions{Tags: []string{}, Genres: []string{}, Developers: []string{}, AppIDs: []int{}}
+	for rows.Next() {
+		var kind models.ExclusionKind
+		var value string
+		if err := rows.Scan(&kind, &value); err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case models.ExcludeTag:
+			exclusions.Tags = append(exclusions.Tags, value)
+		case models.ExcludeGenre:
+			exclusions.Genres = append(exclusions.Genres, value)
+		case models.ExcludeDeveloper:
+			exclusions.Developers = append(exclusions.Developers, value)
+		case models.ExcludeGame:
+			if appId, err := strconv.Atoi(value); err == nil {
+				exclusions.AppIDs = append(exclusions.AppIDs, appId)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exclusions, nil
+}
+
+// exec runs statement once for every exclusion with the user id, kind and
+// value as its parameters.
+func (er *ExclusionRepository) exec(ctx context.Context, id string, exclusions *models.Exclusions, statement string) error {
+	conn, err := er.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	queue := func(kind models.ExclusionKind, values []string) {
+		for _, value := range values {
+			batch.Queue(statement, id, string(kind), value)
+		}
+	}
+	queue(models.ExcludeTag, exclusions.Tags)
+	queue(models.ExcludeGenre, exclusions.Genres)
+	queue(models.ExcludeDeveloper, exclusions.Developers)
+	for _, appId := range exclusions.AppIDs {
+		batch.Queue(statement, id, string(models.ExcludeGame), strconv.Itoa(appId))
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	for range batch.Len() {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	br.Close()
+
+	return tx.Commit(ctx)
+}
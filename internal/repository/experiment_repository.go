@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type ExperimentRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewExperimentRepository(pool *pgxpool.Pool) *ExperimentRepository {
+	return &ExperimentRepository{
+		Pool: pool,
+	}
+}
+
+// Report counts the impressions, swipes and likes tagged with each variant of
+// the experiment. The like rate is the share of swipes that were likes.
+func (er *ExperimentRepository) Report(ctx context.Context, experiment string) ([]models.VariantReport, error) {
+	conn, err := er.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	reports := make(map[string]*models.VariantReport)
+	report := func(variant string) *models.VariantReport {
+		if reports[variant] == nil {
+			reports[variant] = &models.VariantReport{Variant: variant}
+		}
+		return reports[variant]
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT variant, COUNT(*) FROM impressions
+		WHERE experiment = $1
+		GROUP BY variant
+	`, experiment)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var variant string
+		var impressions int
+		if err := rows.Scan(&variant, &impressions); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report(variant).Impressions = impressions
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	actions := make([]string, 0, len(models.LikedActions))
+	for _, action := range models.LikedActions {
+		actions = append(actions, string(action))
+	}
+
+	rows, err = conn.Query(ctx, `
+		SELECT variant, COUNT(*), COUNT(*) FILTER (WHERE action = ANY($2)) FROM swipe_events
+		WHERE experiment = $1
+		GROUP BY variant
+	`, experiment, actions)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var variant string
+		var swipes, likes int
+		if err := rows.Scan(&variant, &swipes, &likes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r := report(variant)
+		r.Swipes, r.Likes = swipes, likes
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.VariantReport, 0, len(reports))
+	for _, r := range reports {
+		if r.Swipes > 0 {
+			r.LikeRate = float64(r.Likes) / float64(r.Swipes)
+		}
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Variant < result[j].Variant })
+	return result, nil
+}
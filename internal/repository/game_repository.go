@@ -12,12 +12,16 @@ import (
 )
 
 type GameRepository struct {
-	Pool *pgxpool.Pool
+	Pool     *pgxpool.Pool
+	PgVector bool
+	EfSearch int
 }
 
-func NewGamesRepository(pool *pgxpool.Pool) *GameRepository {
+func NewGamesRepository(pool *pgxpool.Pool, pgVector bool, efSearch int) *GameRepository {
 	return &GameRepository{
-		Pool: pool,
+		Pool:     pool,
+		PgVector: pgVector,
+		EfSearch: efSearch,
 	}
 }
 
@@ -38,8 +42,8 @@ func (gr *GameRepository) BatchInsert(ctx context.Context, games []*models.Game)
 
 	for _, game := range games {
 		batch.Queue(`
-			INSERT INTO Games (appid, name, short_description, price, initial_price, discount, release_date, genres, tags, positive, negative, platforms, feature_vector)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			INSERT INTO Games (appid, name, short_description, price, initial_price, discount, release_date, genres, tags, positive, negative, platforms, feature_vector, dlc, parent_appid, developers)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13::float8[], $14, NULLIF($15, 0), $16)
 			ON CONFLICT (appid) DO UPDATE SET
 				name = $2,
 				short_description = $3,
@@ -53,8 +57,11 @@ func (gr *GameRepository) BatchInsert(ctx context.Context, games []*models.Game)
 				negative = $11,
 				platforms = $12,
 				feature_vector = $13,
+				dlc = $14,
+				parent_appid = NULLIF($15, 0),
+				developers = $16,
 				last_updated = CURRENT_TIMESTAMP
-		`, game.AppId, game.Name, game.ShortDesc, game.Price, game.InitialPrice, game.Discount, game.ReleaseDate, game.Genres, game.Tags, game.Positive, game.Negative, game.Platforms, game.FeatureVector)
+		`, game.AppId, game.Name, game.ShortDesc, game.Price, game.InitialPrice, game.Discount, game.ReleaseDate, game.Genres, game.Tags, game.Positive, game.Negative, game.Platforms, game.FeatureVector, game.DLC, game.ParentAppId, game.Developers)
 	}
 
 	br := tx.SendBatch(ctx, batch)
@@ -75,53 +82,67 @@ func (gr *GameRepository) BatchInsert(ctx context.Context, games []*models.Game)
 	return nil
 }
 
-func (gr *GameRepository) GetAll(ctx context.Context, priceRange *models.PriceRange, releaseDate *models.ReleaseDate, tags []string, genres []string, platforms []string) ([]models.Game, error) {
+func (gr *GameRepository) GetAll(ctx context.Context, filter *models.GameFilter) ([]models.Game, error) {
 
-	args := []any{priceRange.Min, priceRange.Max}
-	query := []string{`
-		SELECT appid, name, short_description, price, initial_price, discount, 
-           release_date, genres, tags, positive, negative, platforms, feature_vector
+	query, args := appendFilters([]string{fmt.Sprintf(`
+		SELECT %s
     FROM Games
-    WHERE price BETWEEN $1 AND $2
-	`}
+	`, gr.gameColumns())}, nil, filter)
 
-	paramCount := 3
-
-	if releaseDate != nil {
-		dateOperator := ">"
-		if releaseDate.IsBefore {
-			dateOperator = "<"
-		}
-		args = append(args, releaseDate.Date)
-		query = append(query, fmt.Sprintf("AND release_date %s $%d", dateOperator, paramCount))
-		paramCount++
+	conn, err := gr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Release()
 
-	if tags != nil {
-		args = append(args, tags)
-		query = append(query, fmt.Sprintf("AND tags ?| $%d", paramCount))
-		paramCount++
+	rows, err := conn.Query(ctx, strings.Join(query, "\n"), args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	var games []models.Game
 
-	if genres != nil {
-		args = append(args, genres)
-		query = append(query, fmt.Sprintf("AND genres && $%d", paramCount))
-		paramCount++
+	for rows.Next() {
+		var game models.Game
+		if err := scanGame(rows, &game); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
 	}
 
-	if platforms != nil {
-		args = append(args, platforms)
-		query = append(query, fmt.Sprintf("AND platforms && $%d", paramCount))
-		paramCount++
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
+	return games, nil
+}
+
+func (gr *GameRepository) GetRandom(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error) {
+
+	query, args := appendFilters([]string{fmt.Sprintf(`
+		SELECT %s
+    FROM Games
+	`, gr.gameColumns())}, []any{limit}, filter)
+
+	query = append(query, `
+		ORDER BY RANDOM()
+		LIMIT $1
+	`)
+
 	conn, err := gr.Pool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx, strings.Join(query, "\n"), args...)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, strings.Join(query, "\n"), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -131,32 +152,9 @@ func (gr *GameRepository) GetAll(ctx context.Context, priceRange *models.PriceRa
 
 	for rows.Next() {
 		var game models.Game
-		var tagsJSON []byte
-
-		err := rows.Scan(
-			&game.AppId,
-			&game.Name,
-			&game.ShortDesc,
-			&game.Price,
-			&game.InitialPrice,
-			&game.Discount,
-			&game.ReleaseDate,
-			&game.Genres,
-			&tagsJSON, // Scan JSONB as []byte first
-			&game.Positive,
-			&game.Negative,
-			&game.Platforms,
-			&game.FeatureVector,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Parse the JSONB tags
-		if err := json.Unmarshal(tagsJSON, &game.Tags); err != nil {
+		if err := scanGame(rows, &game); err != nil {
 			return nil, err
 		}
-
 		games = append(games, game)
 	}
 
@@ -164,52 +162,69 @@ func (gr *GameRepository) GetAll(ctx context.Context, priceRange *models.PriceRa
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return games, nil
 }
 
-func (gr *GameRepository) GetRandom(ctx context.Context, limit int, priceRange *models.PriceRange, releaseDate *models.ReleaseDate, tags []string, genres []string, platforms []string) ([]models.Game, error) {
+// GetPopular returns the limit games matching filter with the most reviews.
+func (gr *GameRepository) GetPopular(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error) {
 
-	args := []any{limit, priceRange.Min, priceRange.Max}
-	query := []string{`
-		SELECT appid, name, short_description, price, initial_price, discount, 
-           release_date, genres, tags, positive, negative, platforms, feature_vector
+	query, args := appendFilters([]string{fmt.Sprintf(`
+		SELECT %s
     FROM Games
-    WHERE price BETWEEN $2 AND $3
-	`}
+	`, gr.gameColumns())}, []any{limit}, filter)
 
-	paramCount := 4
+	query = append(query, `
+		ORDER BY positive + negative DESC, appid
+		LIMIT $1
+	`)
 
-	if releaseDate != nil {
-		dateOperator := ">"
-		if releaseDate.IsBefore {
-			dateOperator = "<"
-		}
-		args = append(args, releaseDate.Date)
-		query = append(query, fmt.Sprintf("AND release_date %s $%d", dateOperator, paramCount))
-		paramCount++
+	conn, err := gr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Release()
 
-	if tags != nil {
-		args = append(args, tags)
-		query = append(query, fmt.Sprintf("AND tags ?| $%d", paramCount))
-		paramCount++
+	rows, err := conn.Query(ctx, strings.Join(query, "\n"), args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if genres != nil {
-		args = append(args, genres)
-		query = append(query, fmt.Sprintf("AND genres && $%d", paramCount))
-		paramCount++
+	var games []models.Game
+
+	for rows.Next() {
+		var game models.Game
+		if err := scanGame(rows, &game); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
 	}
 
-	if platforms != nil {
-		args = append(args, platforms)
-		query = append(query, fmt.Sprintf("AND platforms && $%d", paramCount))
-		paramCount++
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
+	return games, nil
+}
+
+// SearchByVector returns the k games matching filter whose feature vectors
+// have the highest inner product with vector. It requires the pgvector
+// columns and HNSW index created by EnablePgvector.
+func (gr *GameRepository) SearchByVector(ctx context.Context, vector []float64, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+
+	query, args := appendFilters([]string{fmt.Sprintf(`
+		SELECT %s, -(feature_vector <#> $1::float8[]::vector) AS score
+    FROM Games
+	`, gr.gameColumns())}, []any{vector, k}, filter)
+
 	query = append(query, `
-		ORDER BY RANDOM()
-		LIMIT $1
+		AND feature_vector IS NOT NULL
+		ORDER BY feature_vector <#> $1::float8[]::vector
+		LIMIT $2
 	`)
 
 	conn, err := gr.Pool.Acquire(ctx)
@@ -224,89 +239,115 @@ func (gr *GameRepository) GetRandom(ctx context.Context, limit int, priceRange *
 	}
 	defer tx.Rollback(ctx)
 
-	rows, err := tx.Query(ctx, strings.Join(query, "\n"), args...)
-	if err != nil {
+	// The index scan returns at most ef_search rows before the filters are
+	// applied, so it has to cover k plus the games excluded by id
+	efSearch := min(max(gr.EfSearch, k+len(filter.Exclude)), maxEfSearch)
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch)); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var games []models.Game
+	games, err := queryScoredGames(ctx, tx, strings.Join(query, "\n"), args)
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		var game models.Game
-		var tagsJSON []byte
-
-		err := rows.Scan(
-			&game.AppId,
-			&game.Name,
-			&game.ShortDesc,
-			&game.Price,
-			&game.InitialPrice,
-			&game.Discount,
-			&game.ReleaseDate,
-			&game.Genres,
-			&tagsJSON, // Scan JSONB as []byte first
-			&game.Positive,
-			&game.Negative,
-			&game.Platforms,
-			&game.FeatureVector,
-		)
-		if err != nil {
+	// The other filters can still remove most of what the index scan found,
+	// so retry short results as an exact scan
+	if len(games) < k {
+		if _, err := tx.Exec(ctx, "SET LOCAL enable_indexscan = off"); err != nil {
 			return nil, err
 		}
-
-		// Parse the JSONB tags
-		if err := json.Unmarshal(tagsJSON, &game.Tags); err != nil {
+		if games, err = queryScoredGames(ctx, tx, strings.Join(query, "\n"), args); err != nil {
 			return nil, err
 		}
-
-		games = append(games, game)
 	}
 
-	if err := rows.Err(); err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	return games, nil
+}
+
+func queryScoredGames(ctx context.Context, tx pgx.Tx, query string, args []any) ([]models.ScoredGame, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return games, nil
+	var games []models.ScoredGame
+
+	for rows.Next() {
+		var game models.ScoredGame
+		if err := scanGame(rows, &game.Game, &game.Score); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	return games, rows.Err()
 }
 
-func (gr *GameRepository) GetFeatureVecByAppIDs(ctx context.Context, AppIDs []int) ([][]float64, error) {
+func (gr *GameRepository) GetByAppIDs(ctx context.Context, appIds []int) ([]models.Game, error) {
+	conn, err := gr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
 
-	query := `
-		SELECT feature_vector
+	rows, err := conn.Query(ctx, fmt.Sprintf(`
+		SELECT %s
     FROM Games
     WHERE appid = ANY($1)
-	`
+	`, gr.gameColumns()), appIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []models.Game
+	for rows.Next() {
+		var game models.Game
+		if err := scanGame(rows, &game); err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	return games, nil
+}
+
+// GetFeatureVecMap returns the feature vectors of the given games keyed by appid.
+func (gr *GameRepository) GetFeatureVecMap(ctx context.Context, appIds []int) (map[int][]float64, error) {
 	conn, err := gr.Pool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx, query, AppIDs)
+	rows, err := conn.Query(ctx, fmt.Sprintf(`
+		SELECT appid, %s
+    FROM Games
+    WHERE appid = ANY($1)
+	`, vectorColumn("feature_vector", gr.PgVector)), appIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var vectors [][]float64
-
+	vectors := make(map[int][]float64, len(appIds))
 	for rows.Next() {
+		var appId int
 		var vector []float64
-
-		err := rows.Scan(
-			&vector,
-		)
-		if err != nil {
+		if err := rows.Scan(&appId, &vector); err != nil {
 			return nil, err
 		}
-
-		vectors = append(vectors, vector)
+		vectors[appId] = vector
 	}
 
 	if err := rows.Err(); err != nil {
@@ -352,11 +393,14 @@ func (gr *GameRepository) GetAllTags(ctx context.Context) ([]string, error) {
 	return allTags, nil
 }
 
+// GetAllGenres returns the names of every genre in the catalog, as matched by
+// the genre filters.
 func (gr *GameRepository) GetAllGenres(ctx context.Context) ([]string, error) {
 	query := `
-        SELECT DISTINCT UNNEST(genres) 
-        FROM Games 
-        WHERE genres IS NOT NULL
+        SELECT DISTINCT name
+        FROM Games, unnest(` + genreNames + `) AS name
+        WHERE name <> ''
+        ORDER BY name
     `
 
 	conn, err := gr.Pool.Acquire(ctx)
@@ -386,3 +430,109 @@ func (gr *GameRepository) GetAllGenres(ctx context.Context) ([]string, error) {
 
 	return allGenres, nil
 }
+
+func (gr *GameRepository) gameColumns() string {
+	return `appid, name, short_description, price, initial_price, discount,
+           release_date, genres, tags, positive, negative, platforms, ` + vectorColumn("feature_vector", gr.PgVector) + `,
+           COALESCE(dlc, '{}'), COALESCE(parent_appid, 0), COALESCE(developers, '{}')`
+}
+
+// scanGame scans a row selected with gameColumns into game, followed by any
+// extra columns selected after them.
+func scanGame(row pgx.Row, game *models.Game, extra ...any) error {
+	var tagsJSON []byte
+
+	dest := []any{
+		&game.AppId,
+		&game.Name,
+		&game.ShortDesc,
+		&game.Price,
+		&game.InitialPrice,
+		&game.Discount,
+		&game.ReleaseDate,
+		&game.Genres,
+		&tagsJSON, // Scan JSONB as []byte first
+		&game.Positive,
+		&game.Negative,
+		&game.Platforms,
+		&game.FeatureVector,
+		&game.DLC,
+		&game.ParentAppId,
+		&game.Developers,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	// Parse the JSONB tags
+	return json.Unmarshal(tagsJSON, &game.Tags)
+}
+
+// appendFilters appends the WHERE clause for filter to query, numbering its
+// placeholders after the arguments already in args.
+// genreNames is models.GenreNames in SQL: genres holds the comma separated
+// genre string split on spaces, so the names have to be put back together to
+// compare them.
+const genreNames = `ARRAY(
+	SELECT btrim(name) FROM unnest(string_to_array(array_to_string(genres, ' '), ',')) AS name
+)`
+
+func appendFilters(query []string, args []any, filter *models.GameFilter) ([]string, []any) {
+	priceRange := filter.PriceRange
+	if priceRange == nil {
+		priceRange = &models.PriceRange{Min: 0, Max: 10000}
+	}
+	args = append(args, priceRange.Min, priceRange.Max)
+	query = append(query, fmt.Sprintf("WHERE price BETWEEN $%d AND $%d", len(args)-1, len(args)))
+
+	if filter.ReleaseDate != nil {
+		dateOperator := ">"
+		if filter.ReleaseDate.IsBefore {
+			dateOperator = "<"
+		}
+		args = append(args, filter.ReleaseDate.Date)
+		query = append(query, fmt.Sprintf("AND release_date %s $%d", dateOperator, len(args)))
+	}
+
+	if filter.Tags != nil {
+		args = append(args, filter.Tags)
+		query = append(query, fmt.Sprintf("AND tags ?| $%d", len(args)))
+	}
+
+	if filter.Genres != nil {
+		args = append(args, filter.Genres)
+		query = append(query, fmt.Sprintf("AND %s && $%d", genreNames, len(args)))
+	}
+
+	if filter.Platforms != nil {
+		args = append(args, filter.Platforms)
+		query = append(query, fmt.Sprintf("AND platforms && $%d", len(args)))
+	}
+
+	if len(filter.Exclude) > 0 {
+		args = append(args, filter.Exclude)
+		query = append(query, fmt.Sprintf("AND appid <> ALL($%d)", len(args)))
+	}
+
+	if len(filter.ExcludeTags) > 0 {
+		args = append(args, filter.ExcludeTags)
+		query = append(query, fmt.Sprintf("AND NOT COALESCE(tags ?| $%d, false)", len(args)))
+	}
+
+	if len(filter.ExcludeGenres) > 0 {
+		args = append(args, filter.ExcludeGenres)
+		query = append(query, fmt.Sprintf("AND NOT %s && $%d", genreNames, len(args)))
+	}
+
+	if len(filter.ExcludeDevelopers) > 0 {
+		args = append(args, filter.ExcludeDevelopers)
+		query = append(query, fmt.Sprintf("AND NOT COALESCE(developers && $%d, false)", len(args)))
+	}
+
+	if len(filter.ExcludeDLCOf) > 0 {
+		args = append(args, filter.ExcludeDLCOf)
+		query = append(query, fmt.Sprintf("AND COALESCE(parent_appid, 0) <> ALL($%d)", len(args)))
+	}
+
+	return query, args
+}
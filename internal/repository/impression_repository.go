@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type ImpressionRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewImpressionRepository(pool *pgxpool.Pool) *ImpressionRepository {
+	return &ImpressionRepository{
+		Pool: pool,
+	}
+}
+
+func (ir *ImpressionRepository) BatchInsert(ctx context.Context, impressions []models.Impression) error {
+	if len(impressions) == 0 {
+		return nil
+	}
+
+	userIds := make([]string, 0, len(impressions))
+	appIds := make([]int, 0, len(impressions))
+	sources := make([]string, 0, len(impressions))
+	slots := make([]*string, 0, len(impressions))
+	experiments := make([]*string, 0, len(impressions))
+	variants := make([]*string, 0, len(impressions))
+	for _, impression := range impressions {
+		userIds = append(userIds, impression.UserID)
+		appIds = append(appIds, impression.AppID)
+		sources = append(sources, impression.Source)
+		slots = append(slots, nullable(impression.Slot))
+		experiments = append(experiments, nullable(impression.Experiment))
+		variants = append(variants, nullable(impression.Variant))
+	}
+
+	conn, err := ir.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `
+		INSERT INTO impressions (cookie_id, appid, source, slot, experiment, variant)
+		SELECT * FROM UNNEST($1::text[], $2::int[], $3::text[], $4::text[], $5::text[], $6::text[])
+	`, userIds, appIds, sources, slots, experiments, variants)
+
+	return err
+}
+
+// nullable maps empty strings to NULL.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// GetShownSince returns the distinct appids shown to the user at or after since.
+func (ir *ImpressionRepository) GetShownSince(ctx context.Context, id string, since time.Time) ([]int, error) {
+	conn, err := ir.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT DISTINCT appid FROM impressions
+		WHERE cookie_id = $1 AND shown_at >= $2
+	`, id, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appIds []int
+	for rows.Next() {
+		var appId int
+		if err := rows.Scan(&appId); err != nil {
+			return nil, err
+		}
+		appIds = append(appIds, appId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return appIds, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type InterestRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewInterestRepository(pool *pgxpool.Pool) *InterestRepository {
+	return &InterestRepository{
+		Pool: pool,
+	}
+}
+
+// Replace swaps the user's interests for the given ones.
+func (ir *InterestRepository) Replace(ctx context.Context, id string, interests []models.Interest) error {
+	conn, err := ir.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM user_interests WHERE cookie_id = $1`, id)
+	for _, interest := range interests {
+		batch.Queue(`
+			INSERT INTO user_interests (cookie_id, position, centroid, size, tags)
+			VALUES ($1, $2, $3, $4, $5)
+		`, id, interest.Position, interest.Centroid, interest.Size, interest.Tags)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	for range batch.Len() {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	br.Close()
+
+	return tx.Commit(ctx)
+}
+
+func (ir *InterestRepository) GetByUser(ctx context.Context, id string) ([]models.Interest, error) {
+	conn, err := ir.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT cookie_id, position, centroid, size, tags FROM user_interests
+		WHERE cookie_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interests []models.Interest
+	for rows.Next() {
+		var interest models.Interest
+		if err := rows.Scan(&interest.UserID, &interest.Position, &interest.Centroid, &interest.Size, &interest.Tags); err != nil {
+			return nil, err
+		}
+		interests = append(interests, interest)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return interests, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgvector rejects an hnsw.ef_search above this
+const maxEfSearch = 1000
+
+// vectorColumn returns the select expression for a vector column. pgvector
+// columns are cast back to float8[] so they scan into []float64 either way.
+func vectorColumn(column string, pgVector bool) string {
+	if !pgVector {
+		return column
+	}
+	return column + "::real[]::float8[]"
+}
+
+// EnablePgvector installs the pgvector extension, converts the feature and
+// preference vector columns to vector(dim), also when they were created with
+// another size, and builds an HNSW index for inner product search. It is safe
+// to run on every startup. Stored vectors whose length doesn't match dim are
+// cleared and get refilled by the next populate run or preference update.
+func EnablePgvector(ctx context.Context, pool *pgxpool.Pool, dim int) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		convertColumn("games", "feature_vector", dim),
+		convertColumn("users", "preference_vector", dim),
+		`CREATE INDEX IF NOT EXISTS games_feature_vector_hnsw_idx
+			ON Games USING hnsw (feature_vector vector_ip_ops)`,
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	for _, statement := range statements {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to enable pgvector: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// convertColumn converts a float8[] column to vector(dim), or re-types a
+// vector column of another size, such as after HYBRID_VECTOR is toggled.
+func convertColumn(table string, column string, dim int) string {
+	return fmt.Sprintf(`
+		DO $$
+		DECLARE
+			current_type name;
+			current_dim integer;
+		BEGIN
+			SELECT t.typname, a.atttypmod INTO current_type, current_dim
+			FROM pg_attribute a JOIN pg_type t ON t.oid = a.atttypid
+			WHERE a.attrelid = '%[1]s'::regclass AND a.attname = '%[2]s';
+
+			IF current_type <> 'vector' THEN
+				ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE vector(%[3]d)
+				USING CASE WHEN cardinality(%[2]s) = %[3]d THEN %[2]s::vector(%[3]d) END;
+			ELSIF current_dim <> %[3]d THEN
+				ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE vector(%[3]d)
+				USING CASE WHEN vector_dims(%[2]s) = %[3]d THEN %[2]s::vector(%[3]d) END;
+			END IF;
+		END
+		$$`, table, column, dim)
+}
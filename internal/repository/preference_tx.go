@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+// querier is what statements run on: a pooled connection or a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PreferenceTx is a transaction holding the lock on one user's row, so their
+// swipe events, swipe history and preference state change together and
+// concurrent updates for the same user wait their turn.
+type PreferenceTx struct {
+	tx       pgx.Tx
+	id       string
+	pgVector bool
+}
+
+// BeginPreferenceUpdate starts a transaction and locks the user's row. It
+// fails with ErrUserNotFound when there is no such user.
+func (ur *UserRepository) BeginPreferenceUpdate(ctx context.Context, id string) (*PreferenceTx, error) {
+	tx, err := ur.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked string
+	err = tx.QueryRow(ctx, `
+		SELECT cookie_id FROM Users
+		WHERE cookie_id = $1
+		FOR UPDATE
+	`, id).Scan(&locked)
+	if err != nil {
+		tx.Rollback(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+		}
+		return nil, err
+	}
+
+	return &PreferenceTx{tx: tx, id: id, pgVector: ur.PgVector}, nil
+}
+
+func (pt *PreferenceTx) Commit(ctx context.Context) error {
+	return pt.tx.Commit(ctx)
+}
+
+// Rollback aborts the transaction. It does nothing after Commit, so it can be
+// deferred.
+func (pt *PreferenceTx) Rollback(ctx context.Context) {
+	pt.tx.Rollback(ctx)
+}
+
+// InsertSwipes records the swipe events and appends their games to the
+// user's swipe history. It returns the events that were new: those from a
+// request already recorded for the same game are skipped, so retries don't
+// count twice.
+func (pt *PreferenceTx) InsertSwipes(ctx context.Context, events []models.SwipeEvent) ([]models.SwipeEvent, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	inserted, err := insertSwipes(ctx, pt.tx, events)
+	if err != nil || len(inserted) == 0 {
+		return nil, err
+	}
+
+	appIds := make([]int, 0, len(inserted))
+	for _, event := range inserted {
+		appIds = append(appIds, event.AppID)
+	}
+	_, err = pt.tx.Exec(ctx, `
+		UPDATE Users
+		SET swipe_history = swipe_history || $1
+		WHERE cookie_id = $2
+	`, appIds, pt.id)
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// DeleteLast deletes the user's n latest swipes and returns them.
+func (pt *PreferenceTx) DeleteLast(ctx context.Context, n int) ([]models.SwipeEvent, error) {
+	return pt.deleteSwipes(ctx, `
+		DELETE FROM swipe_events
+		WHERE id IN (
+			SELECT id FROM swipe_events
+			WHERE cookie_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)
+		RETURNING cookie_id, appid, action, created_at, COALESCE(request_id, '')
+	`, pt.id, n)
+}
+
+// DeleteRequest deletes the user's swipes recorded by the given request and
+// returns them.
+func (pt *PreferenceTx) DeleteRequest(ctx context.Context, requestId string) ([]models.SwipeEvent, error) {
+	return pt.deleteSwipes(ctx, `
+		DELETE FROM swipe_events
+		WHERE cookie_id = $1 AND request_id = $2
+		RETURNING cookie_id, appid, action, created_at, COALESCE(request_id, '')
+	`, pt.id, requestId)
+}
+
+// deleteSwipes runs a statement deleting swipe events, then removes their
+// games from the user's swipe history, except those they still have swipe
+// events for.
+func (pt *PreferenceTx) deleteSwipes(ctx context.Context, sql string, args ...any) ([]models.SwipeEvent, error) {
+	deleted, err := querySwipes(ctx, pt.tx, sql, args...)
+	if err != nil || len(deleted) == 0 {
+		return nil, err
+	}
+
+	appIds := make([]int, 0, len(deleted))
+	for _, event := range deleted {
+		appIds = append(appIds, event.AppID)
+	}
+	_, err = pt.tx.Exec(ctx, `
+		UPDATE Users
+		SET swipe_history = ARRAY(
+			SELECT h.appid FROM unnest(swipe_history) WITH ORDINALITY AS h(appid, position)
+			WHERE h.appid <> ALL($1)
+				OR EXISTS (SELECT 1 FROM swipe_events e WHERE e.cookie_id = $2 AND e.appid = h.appid)
+			ORDER BY h.position
+		)
+		WHERE cookie_id = $2
+	`, appIds, pt.id)
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// GetSwipes returns the user's swipe events, oldest first.
+func (pt *PreferenceTx) GetSwipes(ctx context.Context) ([]models.SwipeEvent, error) {
+	return querySwipes(ctx, pt.tx, `
+		SELECT cookie_id, appid, action, created_at, COALESCE(request_id, '') FROM swipe_events
+		WHERE cookie_id = $1
+		ORDER BY created_at, id
+	`, pt.id)
+}
+
+// GetPreference returns the user's current preference vector.
+func (pt *PreferenceTx) GetPreference(ctx context.Context) ([]float64, error) {
+	var preferenceVector []float64
+	err := pt.tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT %s FROM Users
+		WHERE cookie_id = $1
+	`, vectorColumn("preference_vector", pt.pgVector)), pt.id).Scan(&preferenceVector)
+	if err != nil {
+		return nil, err
+	}
+	return preferenceVector, nil
+}
+
+// GetState returns the user's undecayed preference sum and when it was last
+// updated. updatedAt is nil if the state was never computed.
+func (pt *PreferenceTx) GetState(ctx context.Context) ([]float64, *time.Time, error) {
+	var state []float64
+	var updatedAt *time.Time
+
+	err := pt.tx.QueryRow(ctx, `
+		SELECT preference_state, preference_updated_at FROM Users
+		WHERE cookie_id = $1
+	`, pt.id).Scan(&state, &updatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return state, updatedAt, nil
+}
+
+// SetState stores the preference sum as of updatedAt along with the
+// normalized preference vector derived from it.
+func (pt *PreferenceTx) SetState(ctx context.Context, state []float64, updatedAt time.Time, preferenceVector []float64) error {
+	_, err := pt.tx.Exec(ctx, `
+		UPDATE Users
+		SET preference_state = $1,
+			preference_updated_at = $2,
+			preference_vector = $3::float8[]
+		WHERE cookie_id = $4
+	`, state, updatedAt, preferenceVector, pt.id)
+
+	return err
+}
+
+// GetSeed returns the vector the user's preference was seeded with at
+// onboarding and when. seededAt is nil if the user was never onboarded.
+func (pt *PreferenceTx) GetSeed(ctx context.Context) ([]float64, *time.Time, error) {
+	var seed []float64
+	var seededAt *time.Time
+
+	err := pt.tx.QueryRow(ctx, `
+		SELECT preference_seed, preference_seeded_at FROM Users
+		WHERE cookie_id = $1
+	`, pt.id).Scan(&seed, &seededAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return seed, seededAt, nil
+}
+
+func (pt *PreferenceTx) SetSeed(ctx context.Context, seed []float64, seededAt time.Time) error {
+	_, err := pt.tx.Exec(ctx, `
+		UPDATE Users
+		SET preference_seed = $1,
+			preference_seeded_at = $2
+		WHERE cookie_id = $3
+	`, seed, seededAt, pt.id)
+
+	return err
+}
+
+// GetBaseline returns the preference the user had learned before swipes were
+// recorded as events and when it was carried over. baselineAt is nil if it
+// never was.
+func (pt *PreferenceTx) GetBaseline(ctx context.Context) ([]float64, *time.Time, error) {
+	var baseline []float64
+	var baselineAt *time.Time
+
+	err := pt.tx.QueryRow(ctx, `
+		SELECT preference_baseline, preference_baseline_at FROM Users
+		WHERE cookie_id = $1
+	`, pt.id).Scan(&baseline, &baselineAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return baseline, baselineAt, nil
+}
+
+func (pt *PreferenceTx) SetBaseline(ctx context.Context, baseline []float64, baselineAt time.Time) error {
+	_, err := pt.tx.Exec(ctx, `
+		UPDATE Users
+		SET preference_baseline = $1,
+			preference_baseline_at = $2
+		WHERE cookie_id = $3
+	`, baseline, baselineAt, pt.id)
+
+	return err
+}
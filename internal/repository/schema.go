@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schema creates the tables added on top of Games, Games_media and Users.
+// Every statement must be idempotent as they all run on each startup.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS impressions (
+		id BIGSERIAL PRIMARY KEY,
+		cookie_id TEXT NOT NULL,
+		appid INTEGER NOT NULL,
+		source TEXT NOT NULL,
+		slot TEXT,
+		shown_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS impressions_cookie_id_shown_at_idx
+		ON impressions (cookie_id, shown_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS swipe_events (
+		id BIGSERIAL PRIMARY KEY,
+		cookie_id TEXT NOT NULL,
+		appid INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS swipe_events_cookie_id_created_at_idx
+		ON swipe_events (cookie_id, created_at)`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_state float8[]`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_updated_at TIMESTAMPTZ`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_seed float8[]`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_seeded_at TIMESTAMPTZ`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_baseline float8[]`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS preference_baseline_at TIMESTAMPTZ`,
+	`ALTER TABLE Users ADD COLUMN IF NOT EXISTS platforms TEXT[]`,
+	`ALTER TABLE Games ADD COLUMN IF NOT EXISTS dlc INTEGER[]`,
+	`ALTER TABLE Games ADD COLUMN IF NOT EXISTS parent_appid INTEGER`,
+	`ALTER TABLE Games ADD COLUMN IF NOT EXISTS developers TEXT[]`,
+	`CREATE TABLE IF NOT EXISTS user_interests (
+		cookie_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		centroid float8[] NOT NULL,
+		size INTEGER NOT NULL,
+		tags TEXT[] NOT NULL,
+		PRIMARY KEY (cookie_id, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS item_similarity (
+		appid INTEGER NOT NULL,
+		neighbor INTEGER NOT NULL,
+		similarity DOUBLE PRECISION NOT NULL,
+		support INTEGER NOT NULL,
+		PRIMARY KEY (appid, neighbor)
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_exclusions (
+		cookie_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (cookie_id, kind, value)
+	)`,
+	`ALTER TABLE impressions ADD COLUMN IF NOT EXISTS experiment TEXT`,
+	`ALTER TABLE impressions ADD COLUMN IF NOT EXISTS variant TEXT`,
+	`ALTER TABLE swipe_events ADD COLUMN IF NOT EXISTS experiment TEXT`,
+	`ALTER TABLE swipe_events ADD COLUMN IF NOT EXISTS variant TEXT`,
+	`ALTER TABLE swipe_events ADD COLUMN IF NOT EXISTS request_id TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS swipe_events_request_appid_idx
+		ON swipe_events (cookie_id, request_id, appid)`,
+	`CREATE INDEX IF NOT EXISTS impressions_experiment_idx
+		ON impressions (experiment, variant) WHERE experiment IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS swipe_events_experiment_idx
+		ON swipe_events (experiment, variant) WHERE experiment IS NOT NULL`,
+	`CREATE TABLE IF NOT EXISTS rec_sessions (
+		id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+		cookie_id TEXT NOT NULL,
+		items JSONB NOT NULL,
+		experiment TEXT,
+		variant TEXT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS rec_sessions_expires_at_idx ON rec_sessions (expires_at)`,
+}
+
+// EnsureSchema creates any missing tables and indexes.
+func EnsureSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	for _, statement := range schema {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to apply schema: %w", err)
+		}
+	}
+
+	return nil
+}
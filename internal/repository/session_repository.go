@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type SessionRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewSessionRepository(pool *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{
+		Pool: pool,
+	}
+}
+
+// Create stores the session and returns its generated id.
+func (sr *SessionRepository) Create(ctx context.Context, session *models.RecSession) (string, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	var id string
+	err = conn.QueryRow(ctx, `
+		INSERT INTO rec_sessions (cookie_id, items, experiment, variant, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, session.UserID, session.Items, nullable(session.Experiment), nullable(session.Variant), session.ExpiresAt).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Get returns the user's session with the given id, or nil if there is no
+// such session or it has expired.
+func (sr *SessionRepository) Get(ctx context.Context, id string, userId string) (*models.RecSession, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	session := &models.RecSession{ID: id, UserID: userId}
+	var experiment, variant *string
+	err = conn.QueryRow(ctx, `
+		SELECT items, experiment, variant, expires_at FROM rec_sessions
+		WHERE id = $1 AND cookie_id = $2 AND expires_at > now()
+	`, id, userId).Scan(&session.Items, &experiment, &variant, &session.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if experiment != nil {
+		session.Experiment = *experiment
+	}
+	if variant != nil {
+		session.Variant = *variant
+	}
+	return session, nil
+}
+
+// DeleteExpired deletes every expired session and returns how many there were.
+func (sr *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `DELETE FROM rec_sessions WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type SimilarityRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewSimilarityRepository(pool *pgxpool.Pool) *SimilarityRepository {
+	return &SimilarityRepository{
+		Pool: pool,
+	}
+}
+
+// Replace swaps the whole item similarity table for the given pairs.
+func (sr *SimilarityRepository) Replace(ctx context.Context, similarities []models.ItemSimilarity) error {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM item_similarity`); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"item_similarity"},
+		[]string{"appid", "neighbor", "similarity", "support"},
+		pgx.CopyFromSlice(len(similarities), func(i int) ([]any, error) {
+			s := similarities[i]
+			return []any{s.AppID, s.Neighbor, s.Similarity, s.Support}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetNeighbors returns the stored neighbours of the given games.
+func (sr *SimilarityRepository) GetNeighbors(ctx context.Context, appIds []int) ([]models.ItemSimilarity, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT appid, neighbor, similarity, support FROM item_similarity
+		WHERE appid = ANY($1)
+	`, appIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var similarities []models.ItemSimilarity
+	for rows.Next() {
+		var s models.ItemSimilarity
+		if err := rows.Scan(&s.AppID, &s.Neighbor, &s.Similarity, &s.Support); err != nil {
+			return nil, err
+		}
+		similarities = append(similarities, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return similarities, nil
+}
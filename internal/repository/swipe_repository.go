@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+type SwipeRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func NewSwipeRepository(pool *pgxpool.Pool) *SwipeRepository {
+	return &SwipeRepository{
+		Pool: pool,
+	}
+}
+
+// GetByUser returns the user's swipe events, oldest first.
+func (sr *SwipeRepository) GetByUser(ctx context.Context, id string) ([]models.SwipeEvent, error) {
+	return sr.query(ctx, `
+		SELECT cookie_id, appid, action, created_at, COALESCE(request_id, '') FROM swipe_events
+		WHERE cookie_id = $1
+		ORDER BY created_at, id
+	`, id)
+}
+
+// query runs a statement returning swipe events as (user, appid, action,
+// created_at, request_id) rows.
+func (sr *SwipeRepository) query(ctx context.Context, sql string, args ...any) ([]models.SwipeEvent, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	return querySwipes(ctx, conn, sql, args...)
+}
+
+// GetAllLikes returns every user's liked games as distinct (user, game) pairs,
+// grouped by user and most recent first within each user.
+func (sr *SwipeRepository) GetAllLikes(ctx context.Context) ([]models.SwipeEvent, error) {
+	conn, err := sr.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	actions := make([]string, 0, len(models.LikedActions))
+	for _, action := range models.LikedActions {
+		actions = append(actions, string(action))
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT cookie_id, appid, MAX(created_at) AS liked_at FROM swipe_events
+		WHERE action = ANY($1)
+		GROUP BY cookie_id, appid
+		ORDER BY cookie_id, liked_at DESC
+	`, actions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.SwipeEvent
+	for rows.Next() {
+		event := models.SwipeEvent{Action: models.SwipeLike}
+		if err := rows.Scan(&event.UserID, &event.AppID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// insertSwipes records the events and returns those that were new. Events
+// from a request already recorded for the same user and game are skipped.
+func insertSwipes(ctx context.Context, q querier, events []models.SwipeEvent) ([]models.SwipeEvent, error) {
+	userIds := make([]string, 0, len(events))
+	appIds := make([]int, 0, len(events))
+	actions := make([]string, 0, len(events))
+	createdAt := make([]time.Time, 0, len(events))
+	experiments := make([]*string, 0, len(events))
+	variants := make([]*string, 0, len(events))
+	requestIds := make([]*string, 0, len(events))
+	for _, event := range events {
+		userIds = append(userIds, event.UserID)
+		appIds = append(appIds, event.AppID)
+		actions = append(actions, string(event.Action))
+		createdAt = append(createdAt, event.CreatedAt)
+		experiments = append(experiments, nullable(event.Experiment))
+		variants = append(variants, nullable(event.Variant))
+		requestIds = append(requestIds, nullable(event.RequestID))
+	}
+
+	return querySwipes(ctx, q, `
+		INSERT INTO swipe_events (cookie_id, appid, action, created_at, experiment, variant, request_id)
+		SELECT * FROM UNNEST($1::text[], $2::int[], $3::text[], $4::timestamptz[], $5::text[], $6::text[], $7::text[])
+		ON CONFLICT (cookie_id, request_id, appid) DO NOTHING
+		RETURNING cookie_id, appid, action, created_at, COALESCE(request_id, '')
+	`, userIds, appIds, actions, createdAt, experiments, variants, requestIds)
+}
+
+func querySwipes(ctx context.Context, q querier, sql string, args ...any) ([]models.SwipeEvent, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.SwipeEvent
+	for rows.Next() {
+		var event models.SwipeEvent
+		if err := rows.Scan(&event.UserID, &event.AppID, &event.Action, &event.CreatedAt, &event.RequestID); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -2,47 +2,68 @@ package repository
 
 import (
 	"context"
-	"os"
-	"strconv"
+	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ty4g1/gamescout_backend/internal/models"
 )
 
+// ErrUserNotFound is returned by lookups of a cookie id with no user.
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
-	Pool *pgxpool.Pool
+	Pool     *pgxpool.Pool
+	PgVector bool
 }
 
-func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
+func NewUserRepository(pool *pgxpool.Pool, pgVector bool) *UserRepository {
 	return &UserRepository{
-		Pool: pool,
+		Pool:     pool,
+		PgVector: pgVector,
 	}
 }
 
-func (ur *UserRepository) AddUser(ctx context.Context, id string) (*models.User, error) {
-	VECTOR_DIM, err := strconv.Atoi(os.Getenv("VECTOR_DIM"))
-	if err != nil {
-		VECTOR_DIM = 384
-	}
-	conn, err := ur.Pool.Acquire(ctx)
+// AddUser creates the user, or resets an existing one to a blank slate:
+// their swipes, preference and interests are all dropped together.
+func (ur *UserRepository) AddUser(ctx context.Context, id string, vectorDim int) (*models.User, error) {
+	tx, err := ur.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Release()
+	defer tx.Rollback(ctx)
 
 	var user models.User
 
-	err = conn.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
 		INSERT INTO Users (cookie_id, swipe_history, preference_vector)
-		VALUES ($1, $2, $3)
+		VALUES ($1, $2, $3::float8[])
 		ON CONFLICT (cookie_id) DO UPDATE SET
 			swipe_history = $2,
-			preference_vector = $3
-		RETURNING cookie_id, swipe_history, preference_vector
-	`, id, []string{}, make([]float64, VECTOR_DIM)).Scan(&user.ID, &user.SwipeHistory, &user.PreferenceVector)
+			preference_vector = $3::float8[],
+			preference_state = NULL,
+			preference_updated_at = NULL,
+			preference_seed = NULL,
+			preference_seeded_at = NULL,
+			preference_baseline = NULL,
+			preference_baseline_at = NULL
+		RETURNING cookie_id, swipe_history, %s
+	`, vectorColumn("preference_vector", ur.PgVector)), id, []string{}, make([]float64, vectorDim)).Scan(&user.ID, &user.SwipeHistory, &user.PreferenceVector)
 	if err != nil {
 		return nil, err
 	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM swipe_events WHERE cookie_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_interests WHERE cookie_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
@@ -55,10 +76,13 @@ func (ur *UserRepository) GetUserPreference(ctx context.Context, id string) ([]f
 
 	var preferenceVector []float64
 
-	err = conn.QueryRow(ctx, `
-		SELECT preference_vector FROM Users
+	err = conn.QueryRow(ctx, fmt.Sprintf(`
+		SELECT %s FROM Users
 		WHERE cookie_id = $1
-	`, id).Scan(&preferenceVector)
+	`, vectorColumn("preference_vector", ur.PgVector)), id).Scan(&preferenceVector)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -78,48 +102,68 @@ func (ur *UserRepository) GetUserSwipes(ctx context.Context, id string) ([]int,
 		SELECT swipe_history FROM Users
 		WHERE cookie_id = $1
 	`, id).Scan(&swipeHistory)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return swipeHistory, nil
 }
 
-func (ur *UserRepository) UpdateUserPreference(ctx context.Context, id string, preferenceVector []float64) error {
+// GetPlatforms returns the platforms set by each of the users. Users who
+// haven't set theirs are left out.
+func (ur *UserRepository) GetPlatforms(ctx context.Context, ids []string) (map[string][]string, error) {
 	conn, err := ur.Pool.Acquire(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, `
-		UPDATE Users
-		SET preference_vector = $1
-		WHERE cookie_id = $2
-	`, preferenceVector, id)
-
+	rows, err := conn.Query(ctx, `
+		SELECT cookie_id, platforms FROM Users
+		WHERE cookie_id = ANY($1) AND platforms IS NOT NULL
+	`, ids)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	platforms := make(map[string][]string)
+	for rows.Next() {
+		var id string
+		var userPlatforms []string
+		if err := rows.Scan(&id, &userPlatforms); err != nil {
+			return nil, err
+		}
+		platforms[id] = userPlatforms
 	}
 
-	return nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
 }
 
-func (ur *UserRepository) UpdateUserSwipes(ctx context.Context, id string, swipes []int) error {
+func (ur *UserRepository) UpdatePlatforms(ctx context.Context, id string, platforms []string) error {
 	conn, err := ur.Pool.Acquire(ctx)
 	if err != nil {
 		return err
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, `
+	tag, err := conn.Exec(ctx, `
 		UPDATE Users
-		SET swipe_history = swipe_history || $1
+		SET platforms = $1
 		WHERE cookie_id = $2
-	`, swipes, id)
-
+	`, platforms, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
 
 	return nil
 }
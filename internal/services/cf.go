@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+)
+
+// CollaborativeFilter recommends games liked by users who liked the same
+// games. Build derives an item-item similarity table from co-likes:
+//
+//	sim(i, j) = co(i, j) / sqrt(n(i) * n(j)) * co(i, j) / (co(i, j) + shrinkage)
+//
+// where co(i, j) counts the users who liked both games and n(i) the users who
+// liked i. The shrinkage term pulls pairs with little support towards 0, pairs
+// liked together by fewer than minSupport users are dropped and each game
+// keeps only its maxNeighbors most similar games.
+type CollaborativeFilter struct {
+	sr           *repository.SwipeRepository
+	simr         *repository.SimilarityRepository
+	gr           *repository.GameRepository
+	minSupport   int
+	shrinkage    float64
+	maxNeighbors int
+	maxUserLikes int
+}
+
+func NewCollaborativeFilter(cfg *config.Config, sr *repository.SwipeRepository, simr *repository.SimilarityRepository, gr *repository.GameRepository) *CollaborativeFilter {
+	return &CollaborativeFilter{
+		sr:           sr,
+		simr:         simr,
+		gr:           gr,
+		minSupport:   max(cfg.CFMinSupport, 1),
+		shrinkage:    cfg.CFShrinkage,
+		maxNeighbors: cfg.CFMaxNeighbors,
+		maxUserLikes: cfg.CFMaxUserLikes,
+	}
+}
+
+// Build recomputes the item similarity table from every user's likes and
+// returns the number of pairs stored.
+func (cf *CollaborativeFilter) Build(ctx context.Context) (int, error) {
+	likes, err := cf.sr.GetAllLikes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	similarities := ItemSimilarities(likes, cf.minSupport, cf.shrinkage, cf.maxNeighbors, cf.maxUserLikes)
+	if err := cf.simr.Replace(ctx, similarities); err != nil {
+		return 0, err
+	}
+	return len(similarities), nil
+}
+
+// ItemSimilarities computes the item similarity table from likes, which must
+// be grouped by user and most recent first within each user. A non-positive
+// maxNeighbors or maxUserLikes means no cap.
+func ItemSimilarities(likes []models.SwipeEvent, minSupport int, shrinkage float64, maxNeighbors int, maxUserLikes int) []models.ItemSimilarity {
+	counts := make(map[int]int)
+	co := make(map[[2]int]int)
+	for start := 0; start < len(likes); {
+		end := start
+		for end < len(likes) && likes[end].UserID == likes[start].UserID {
+			end++
+		}
+
+		// Likes are most recent first, so heavy users only count with their
+		// latest likes and can't blow up the number of pairs
+		user := likes[start:end]
+		if maxUserLikes > 0 && len(user) > maxUserLikes {
+			user = user[:maxUserLikes]
+		}
+		for a, i := range user {
+			counts[i.AppID]++
+			for _, j := range user[a+1:] {
+				co[pairKey(i.AppID, j.AppID)]++
+			}
+		}
+		start = end
+	}
+
+	neighbors := make(map[int][]models.ItemSimilarity)
+	for pair, support := range co {
+		if support < minSupport {
+			continue
+		}
+		i, j := pair[0], pair[1]
+		cosine := float64(support) / math.Sqrt(float64(counts[i])*float64(counts[j]))
+		similarity := cosine * float64(support) / (float64(support) + shrinkage)
+
+		neighbors[i] = append(neighbors[i], models.ItemSimilarity{AppID: i, Neighbor: j, Similarity: similarity, Support: support})
+		neighbors[j] = append(neighbors[j], models.ItemSimilarity{AppID: j, Neighbor: i, Similarity: similarity, Support: support})
+	}
+
+	var similarities []models.ItemSimilarity
+	for _, list := range neighbors {
+		sort.Slice(list, func(a, b int) bool {
+			if list[a].Similarity != list[b].Similarity {
+				return list[a].Similarity > list[b].Similarity
+			}
+			return list[a].Neighbor < list[b].Neighbor
+		})
+		if maxNeighbors > 0 && len(list) > maxNeighbors {
+			list = list[:maxNeighbors]
+		}
+		similarities = append(similarities, list...)
+	}
+
+	return similarities
+}
+
+// Scores returns the summed similarity of games to the user's recent likes,
+// scaled so the best game scores 1. Liked games themselves are left out.
+func (cf *CollaborativeFilter) Scores(ctx context.Context, id string) (map[int]float64, error) {
+	events, err := cf.sr.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	liked := RecentLikes(events, cf.maxUserLikes)
+	if len(liked) == 0 {
+		return nil, nil
+	}
+
+	neighbors, err := cf.simr.GetNeighbors(ctx, liked)
+	if err != nil {
+		return nil, err
+	}
+
+	return CFScores(liked, neighbors), nil
+}
+
+// CFScores sums the similarity of games to the liked ones over the liked
+// games' neighbours, scaled so the best game scores 1.
+func CFScores(liked []int, neighbors []models.ItemSimilarity) map[int]float64 {
+	isLiked := make(map[int]bool, len(liked))
+	for _, appId := range liked {
+		isLiked[appId] = true
+	}
+
+	scores := make(map[int]float64)
+	top := 0.0
+	for _, n := range neighbors {
+		if !isLiked[n.Neighbor] {
+			scores[n.Neighbor] += n.Similarity
+			top = max(top, scores[n.Neighbor])
+		}
+	}
+	if top > 0 {
+		for appId := range scores {
+			scores[appId] /= top
+		}
+	}
+	return scores
+}
+
+// Recommend returns up to k games that pass filter, best first by Scores.
+func (cf *CollaborativeFilter) Recommend(ctx context.Context, id string, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+	scores, err := cf.Scores(ctx, id)
+	if err != nil || len(scores) == 0 {
+		return nil, err
+	}
+
+	appIds := make([]int, 0, len(scores))
+	for appId := range scores {
+		appIds = append(appIds, appId)
+	}
+	games, err := cf.gr.GetByAppIDs(ctx, appIds)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := filter.Matcher()
+	scored := make([]models.ScoredGame, 0, len(games))
+	for _, game := range games {
+		if matches(&game) {
+			scored = append(scored, models.ScoredGame{Game: game, Score: scores[game.AppId]})
+		}
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].AppId < scored[j].AppId
+	})
+	return scored[:min(k, len(scored))], nil
+}
+
+func pairKey(i int, j int) [2]int {
+	if i > j {
+		i, j = j, i
+	}
+	return [2]int{i, j}
+}
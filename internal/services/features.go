@@ -0,0 +1,140 @@
+package services
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// Upper bounds (in cents) of every price bucket but the last, which is open
+// ended. The first bucket holds free games only.
+var priceBuckets = []int{0, 500, 1000, 2000, 4000}
+
+// First year of every release era but the first, which holds everything older.
+var releaseEras = []int{2000, 2005, 2010, 2015, 2020, 2025}
+
+var hybridPlatforms = []string{"windows", "mac", "linux"}
+
+var hybridGenres = []string{
+	"Action",
+	"Adventure",
+	"Casual",
+	"Early Access",
+	"Free to Play",
+	"Indie",
+	"Massively Multiplayer",
+	"RPG",
+	"Racing",
+	"Simulation",
+	"Sports",
+	"Strategy",
+}
+
+// Review counts at or above this saturate the log review count feature.
+const maxReviewCount = 1_000_000
+
+type FeatureEncoder struct {
+	Enabled bool
+	Weights config.HybridWeights
+}
+
+func NewFeatureEncoder(cfg *config.Config) *FeatureEncoder {
+	return &FeatureEncoder{
+		Enabled: cfg.HybridVector,
+		Weights: cfg.HybridWeights,
+	}
+}
+
+// FeatureDim returns the length of the feature vectors produced under cfg.
+func FeatureDim(cfg *config.Config) int {
+	if !cfg.HybridVector {
+		return cfg.VectorDim
+	}
+	return cfg.VectorDim + structuredDim()
+}
+
+func structuredDim() int {
+	return len(priceBuckets) + 1 + 2 + len(releaseEras) + 1 + len(hybridPlatforms) + len(hybridGenres)
+}
+
+// Encode returns the feature vector for game. When hybrid vectors are
+// disabled this is the semantic embedding unchanged; otherwise the weighted
+// structured blocks are appended and the whole vector is normalized so that
+// the dot product stays a cosine similarity.
+func (fe *FeatureEncoder) Encode(game *models.Game, semantic []float64) []float64 {
+	if !fe.Enabled {
+		return semantic
+	}
+
+	vector := make([]float64, 0, len(semantic)+structuredDim())
+	for _, val := range utils.NormalizeVector(semantic) {
+		vector = append(vector, val*fe.Weights.Semantic)
+	}
+
+	vector = appendBlock(vector, fe.Weights.Price, priceBlock(game.Price))
+	vector = appendBlock(vector, fe.Weights.Reviews, reviewBlock(game.Positive, game.Negative))
+	vector = appendBlock(vector, fe.Weights.Year, yearBlock(game.ReleaseDate.Year()))
+	vector = appendBlock(vector, fe.Weights.Platforms, multiHot(hybridPlatforms, game.Platforms))
+	vector = appendBlock(vector, fe.Weights.Genres, multiHot(hybridGenres, models.GenreNames(game.Genres)))
+
+	return utils.NormalizeVector(vector)
+}
+
+// appendBlock normalizes block so every block has unit length before its
+// weight is applied, regardless of how many dimensions it spans.
+func appendBlock(vector []float64, weight float64, block []float64) []float64 {
+	for _, val := range utils.NormalizeVector(block) {
+		vector = append(vector, val*weight)
+	}
+	return vector
+}
+
+func priceBlock(price int) []float64 {
+	block := make([]float64, len(priceBuckets)+1)
+	for i, bound := range priceBuckets {
+		if price <= bound {
+			block[i] = 1
+			return block
+		}
+	}
+	block[len(priceBuckets)] = 1
+	return block
+}
+
+func reviewBlock(positive int, negative int) []float64 {
+	total := positive + negative
+	if total == 0 {
+		return []float64{0, 0}
+	}
+	count := math.Min(math.Log1p(float64(total))/math.Log1p(maxReviewCount), 1)
+	ratio := float64(positive) / float64(total)
+	return []float64{count, ratio}
+}
+
+func yearBlock(year int) []float64 {
+	block := make([]float64, len(releaseEras)+1)
+	era := 0
+	for i, start := range releaseEras {
+		if year >= start {
+			era = i + 1
+		}
+	}
+	block[era] = 1
+	return block
+}
+
+func multiHot(vocabulary []string, values []string) []float64 {
+	block := make([]float64, len(vocabulary))
+	for i, word := range vocabulary {
+		for _, val := range values {
+			if strings.EqualFold(word, val) {
+				block[i] = 1
+				break
+			}
+		}
+	}
+	return block
+}
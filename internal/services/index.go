@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/ann"
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// Rebuild from scratch instead of patching once this share of the graph
+// would be deleted nodes.
+const maxDeletedRatio = 0.25
+
+// Times a search short of k results is retried with a doubled ef before
+// falling back to an exact scan.
+const maxSearchRetries = 2
+
+// IndexSearcher answers searches from an in-memory HNSW index over the game
+// catalog, for deployments without pgvector. Call Build before serving and
+// Refresh after every populate run.
+type IndexSearcher struct {
+	gr               *repository.GameRepository
+	m                int
+	efConstruction   int
+	efSearch         int
+	dim              int
+	recallSampleRate float64
+	stats            ann.Stats
+
+	mu    sync.RWMutex
+	index *ann.Index
+	games map[int]models.Game
+}
+
+func NewIndexSearcher(cfg *config.Config, gr *repository.GameRepository) *IndexSearcher {
+	return &IndexSearcher{
+		gr:               gr,
+		m:                cfg.AnnM,
+		efConstruction:   cfg.AnnEfConstruction,
+		efSearch:         cfg.HNSWEfSearch,
+		dim:              FeatureDim(cfg),
+		recallSampleRate: cfg.AnnRecallSampleRate,
+		index:            ann.New(cfg.AnnM, cfg.AnnEfConstruction),
+		games:            map[int]models.Game{},
+	}
+}
+
+// Build loads the whole catalog and replaces the index with a fresh one.
+func (is *IndexSearcher) Build(ctx context.Context) error {
+	start := time.Now()
+
+	games, err := is.loadCatalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := ann.New(is.m, is.efConstruction)
+	for appId, game := range games {
+		index.Add(appId, game.FeatureVector)
+	}
+
+	is.mu.Lock()
+	is.index = index
+	is.games = games
+	is.mu.Unlock()
+
+	is.stats.RecordBuild(start, time.Since(start))
+	return nil
+}
+
+// Refresh reloads the catalog and patches the index with new, changed and
+// removed games, falling back to a full Build when too many nodes would be
+// left deleted.
+func (is *IndexSearcher) Refresh(ctx context.Context) error {
+	start := time.Now()
+
+	games, err := is.loadCatalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	is.mu.RLock()
+	index, current := is.index, is.games
+	is.mu.RUnlock()
+
+	var changed, removed []int
+	for appId, game := range games {
+		old, ok := current[appId]
+		if !ok || !equalVectors(old.FeatureVector, game.FeatureVector) {
+			changed = append(changed, appId)
+		}
+	}
+	for appId := range current {
+		if _, ok := games[appId]; !ok {
+			removed = append(removed, appId)
+		}
+	}
+
+	deleted := index.Deleted() + len(removed)
+	for _, appId := range changed {
+		if _, ok := current[appId]; ok {
+			deleted++
+		}
+	}
+	if float64(deleted) > maxDeletedRatio*float64(index.Len()+deleted) {
+		return is.Build(ctx)
+	}
+
+	for _, appId := range changed {
+		index.Add(appId, games[appId].FeatureVector)
+	}
+
+	is.mu.Lock()
+	is.games = games
+	is.mu.Unlock()
+
+	for _, appId := range removed {
+		index.Delete(appId)
+	}
+
+	is.stats.RecordBuild(start, time.Since(start))
+	return nil
+}
+
+func (is *IndexSearcher) Search(ctx context.Context, vector []float64, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+	start := time.Now()
+
+	is.mu.RLock()
+	index, games := is.index, is.games
+	is.mu.RUnlock()
+
+	matches := filter.Matcher()
+	accept := func(appId int) bool {
+		game, ok := games[appId]
+		return ok && matches(&game)
+	}
+
+	// A restrictive filter can leave the graph search short of k results, so
+	// widen it a few times, then fall back to an exact scan if enough games
+	// match for it to find more
+	ef := max(is.efSearch, k)
+	results := index.Search(vector, k, ef, accept)
+	for retry := 0; len(results) < k && retry < maxSearchRetries; retry++ {
+		ef *= 2
+		results = index.Search(vector, k, ef, accept)
+	}
+	if len(results) < k && countMatches(games, accept) >= k {
+		results = exactSearch(games, vector, k, accept)
+	}
+
+	scored := make([]models.ScoredGame, 0, len(results))
+	for _, res := range results {
+		scored = append(scored, models.ScoredGame{Game: games[res.Key], Score: res.Score})
+	}
+
+	is.stats.RecordQuery(time.Since(start))
+	if rand.Float64() < is.recallSampleRate {
+		go is.sampleRecall(games, vector, k, accept, results)
+	}
+
+	return scored, nil
+}
+
+// Stats returns the index's size, latency, recall and build statistics.
+func (is *IndexSearcher) Stats() ann.StatsSnapshot {
+	is.mu.RLock()
+	index := is.index
+	is.mu.RUnlock()
+
+	snapshot := is.stats.Snapshot()
+	snapshot.Size = index.Len()
+	snapshot.Deleted = index.Deleted()
+	return snapshot
+}
+
+func (is *IndexSearcher) sampleRecall(games map[int]models.Game, vector []float64, k int, accept func(int) bool, results []ann.Result) {
+	exact := exactSearch(games, vector, k, accept)
+	if len(exact) == 0 {
+		return
+	}
+
+	found := make(map[int]bool, len(results))
+	for _, res := range results {
+		found[res.Key] = true
+	}
+
+	hits := 0
+	for _, res := range exact {
+		if found[res.Key] {
+			hits++
+		}
+	}
+	is.stats.RecordRecall(float64(hits) / float64(len(exact)))
+}
+
+// loadCatalog returns every game with a feature vector of the configured
+// length, keyed by appid.
+func (is *IndexSearcher) loadCatalog(ctx context.Context) (map[int]models.Game, error) {
+	all, err := is.gr.GetAll(ctx, &models.GameFilter{
+		PriceRange: &models.PriceRange{Min: 0, Max: math.MaxInt32},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	games := make(map[int]models.Game, len(all))
+	skipped := 0
+	for _, game := range all {
+		if len(game.FeatureVector) != is.dim {
+			skipped++
+			continue
+		}
+		games[game.AppId] = game
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d games without a %d-dim feature vector\n", skipped, is.dim)
+	}
+
+	return games, nil
+}
+
+func countMatches(games map[int]models.Game, accept func(int) bool) int {
+	count := 0
+	for appId := range games {
+		if accept(appId) {
+			count++
+		}
+	}
+	return count
+}
+
+func exactSearch(games map[int]models.Game, vector []float64, k int, accept func(int) bool) []ann.Result {
+	appIds := make([]int, 0, len(games))
+	for appId := range games {
+		if accept(appId) {
+			appIds = append(appIds, appId)
+		}
+	}
+	slices.Sort(appIds)
+
+	top := utils.TopK(len(appIds), k, func(i int) (float64, error) {
+		return utils.ComputeSimilarity(vector, games[appIds[i]].FeatureVector)
+	})
+
+	results := make([]ann.Result, 0, len(top))
+	for _, ranked := range top {
+		results = append(results, ann.Result{Key: appIds[ranked.Index], Score: ranked.Score})
+	}
+	return results
+}
+
+func equalVectors(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+const (
+	kMeansIterations = 20
+	// How many of each liked game's top tags count towards an interest's label
+	tagsPerGame = 5
+	// How many tags label an interest
+	tagsPerInterest = 3
+)
+
+// InterestBuilder clusters the feature vectors of the games a user liked into
+// up to K interest centroids, so users with several distinct tastes get
+// recommendations for each instead of for their average. Clustering is too
+// slow to run on every swipe, so swipes Schedule a rebuild that Run performs
+// in the background.
+type InterestBuilder struct {
+	gr       *repository.GameRepository
+	sr       *repository.SwipeRepository
+	ir       *repository.InterestRepository
+	cache    *RecommendationCache
+	k        int
+	maxLikes int
+	dim      int
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func NewInterestBuilder(cfg *config.Config, gr *repository.GameRepository, sr *repository.SwipeRepository, ir *repository.InterestRepository, cache *RecommendationCache) *InterestBuilder {
+	return &InterestBuilder{
+		gr:       gr,
+		sr:       sr,
+		ir:       ir,
+		cache:    cache,
+		k:        cfg.InterestClusters,
+		maxLikes: cfg.InterestMaxLikes,
+		dim:      FeatureDim(cfg),
+		pending:  make(map[string]bool),
+	}
+}
+
+// Schedule queues a rebuild of the user's interests for Run's next pass.
+// Scheduling a user who is already queued does nothing.
+func (ib *InterestBuilder) Schedule(id string) {
+	if ib.k <= 0 {
+		return
+	}
+	ib.mu.Lock()
+	ib.pending[id] = true
+	ib.mu.Unlock()
+}
+
+// Run rebuilds the interests of the users scheduled since its last pass,
+// every delay until ctx is done. Failures are logged and dropped; the user's
+// next swipe schedules them again.
+func (ib *InterestBuilder) Run(ctx context.Context, delay time.Duration) {
+	ticker := time.NewTicker(max(delay, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ib.mu.Lock()
+		pending := ib.pending
+		ib.pending = make(map[string]bool)
+		ib.mu.Unlock()
+
+		for id := range pending {
+			if _, err := ib.Rebuild(ctx, id); err != nil {
+				log.Printf("Error rebuilding interests for user %s: %v\n", id, err)
+			}
+		}
+	}
+}
+
+// Rebuild reclusters the user's most recent likes and stores the result,
+// dropping the user's recommendations ranked against their old interests.
+func (ib *InterestBuilder) Rebuild(ctx context.Context, id string) ([]models.Interest, error) {
+	if ib.k <= 0 {
+		return nil, nil
+	}
+
+	events, err := ib.sr.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := ib.gr.GetByAppIDs(ctx, RecentLikes(events, ib.maxLikes))
+	if err != nil {
+		return nil, err
+	}
+	games = utils.Filter(games, func(g models.Game) bool {
+		return len(g.FeatureVector) == ib.dim
+	})
+
+	vectors := make([][]float64, 0, len(games))
+	for _, game := range games {
+		vectors = append(vectors, utils.NormalizeVector(game.FeatureVector))
+	}
+
+	centroids, assignments := utils.SphericalKMeans(vectors, ib.k, kMeansIterations)
+
+	interests := make([]models.Interest, len(centroids))
+	members := make([][]models.Game, len(centroids))
+	for i, c := range assignments {
+		members[c] = append(members[c], games[i])
+	}
+	for c, centroid := range centroids {
+		interests[c] = models.Interest{
+			UserID:   id,
+			Position: c,
+			Centroid: centroid,
+			Size:     len(members[c]),
+			Tags:     dominantTags(members[c]),
+		}
+	}
+
+	// Largest interest first
+	sort.SliceStable(interests, func(i, j int) bool { return interests[i].Size > interests[j].Size })
+	for i := range interests {
+		interests[i].Position = i
+	}
+
+	if err := ib.ir.Replace(ctx, id, interests); err != nil {
+		return nil, err
+	}
+	ib.cache.Invalidate(id)
+	return interests, nil
+}
+
+// InterestVectors returns the vectors to rank candidates against for a user
+// with the given preference vector and interests: the preference vector, then
+// each interest's centroid steered by it when they have several. Centroids
+// only cluster likes, so steering them keeps the decay, action weights,
+// dislikes and onboarding seed folded into the preference in every channel.
+func InterestVectors(preference []float64, interests []models.Interest) [][]float64 {
+	if len(interests) < 2 {
+		return [][]float64{preference}
+	}
+
+	vectors := make([][]float64, 0, len(interests)+1)
+	if len(preference) > 0 {
+		vectors = append(vectors, preference)
+	}
+	for _, interest := range interests {
+		steered, err := utils.AddVectors(utils.NormalizeVector(interest.Centroid), utils.NormalizeVector(preference))
+		if err != nil {
+			// Interests from before a change of vector size
+			continue
+		}
+		vectors = append(vectors, utils.NormalizeVector(steered))
+	}
+	return vectors
+}
+
+// RecentLikes returns the appids of up to n distinct games the user liked,
+// most recent first, or all of them if n is not positive. events must be
+// sorted oldest first.
+func RecentLikes(events []models.SwipeEvent, n int) []int {
+	var liked []int
+	seen := make(map[int]bool)
+	for i := len(events) - 1; i >= 0 && (n <= 0 || len(liked) < n); i-- {
+		appId := events[i].AppID
+		if events[i].Action.Liked() && !seen[appId] {
+			liked = append(liked, appId)
+			seen[appId] = true
+		}
+	}
+	return liked
+}
+
+// dominantTags returns the tags that most often appear among the top tags of
+// the given games.
+func dominantTags(games []models.Game) []string {
+	counts := make(map[string]int)
+	for _, game := range games {
+		tags := make([]string, 0, len(game.Tags))
+		for tag := range game.Tags {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if game.Tags[tags[i]] != game.Tags[tags[j]] {
+				return game.Tags[tags[i]] > game.Tags[tags[j]]
+			}
+			return tags[i] < tags[j]
+		})
+		for _, tag := range tags[:min(tagsPerGame, len(tags))] {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	return tags[:min(tagsPerInterest, len(tags))]
+}
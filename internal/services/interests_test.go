@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+func TestInterestVectorsKeepDislikes(t *testing.T) {
+	cfg := &config.Config{
+		VectorDim:    4,
+		SwipeWeights: config.SwipeWeights{Like: 1, Dislike: -1},
+	}
+	vectors := map[int][]float64{
+		1: {1, 0, 0, 0},
+		2: {0, 1, 0, 0},
+		3: {0, 0, 1, 0},
+	}
+	// Likes of games 1 and 2 cluster into one interest each
+	interests := []models.Interest{
+		{Position: 0, Centroid: vectors[1], Size: 1},
+		{Position: 1, Centroid: vectors[2], Size: 1},
+	}
+	now := time.Now()
+	likes := []models.SwipeEvent{
+		{AppID: 1, Action: models.SwipeLike, CreatedAt: now},
+		{AppID: 2, Action: models.SwipeLike, CreatedAt: now},
+	}
+	dislike := models.SwipeEvent{AppID: 3, Action: models.SwipeDislike, CreatedAt: now}
+
+	// A game like the first interest but also like the disliked game
+	candidate := []models.ScoredGame{{Game: models.Game{AppId: 4, FeatureVector: utils.NormalizeVector([]float64{1, 0, 1, 0})}}}
+	score := func(swipes []models.SwipeEvent) float64 {
+		preference := PreferenceFromSwipes(cfg, swipes, vectors, now)
+		req := &ranking.Request{Vectors: InterestVectors(preference, interests)}
+		values, err := ranking.SimilarityExtractor{}.Extract(context.Background(), req, candidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return values[0]
+	}
+
+	liked := score(likes)
+	disliked := score(append(likes, dislike))
+	if disliked >= liked {
+		t.Errorf("similarity after disliking = %f, want below %f", disliked, liked)
+	}
+}
+
+func TestInterestVectors(t *testing.T) {
+	preference := []float64{1, 0}
+
+	if got := InterestVectors(preference, nil); len(got) != 1 || got[0][0] != 1 {
+		t.Errorf("without interests got %v, want just the preference", got)
+	}
+
+	interests := []models.Interest{{Centroid: []float64{0, 1}}, {Centroid: []float64{0, -1}}, {Centroid: []float64{1, 0, 0}}}
+	got := InterestVectors(preference, interests)
+	// The preference, then the centroids of the right size steered by it
+	if len(got) != 3 {
+		t.Fatalf("got %d vectors, want 3", len(got))
+	}
+	if got[0][0] != 1 || got[0][1] != 0 {
+		t.Errorf("first vector = %v, want the preference", got[0])
+	}
+	if got[1][0] <= 0 || got[1][1] <= 0 || got[2][0] <= 0 || got[2][1] >= 0 {
+		t.Errorf("steered centroids = %v, want between each centroid and the preference", got[1:])
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/ranking"
+)
+
+// Catalog is where the generators that don't need a query vector find games.
+// GameRepository serves it from Postgres.
+type Catalog interface {
+	GetAll(ctx context.Context, filter *models.GameFilter) ([]models.Game, error)
+	GetPopular(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error)
+	GetRandom(ctx context.Context, limit int, filter *models.GameFilter) ([]models.Game, error)
+}
+
+// CFSource scores games by collaborative filtering. CollaborativeFilter
+// serves it from the stored similarity table.
+type CFSource interface {
+	Scores(ctx context.Context, id string) (map[int]float64, error)
+	Recommend(ctx context.Context, id string, k int, filter *models.GameFilter) ([]models.ScoredGame, error)
+}
+
+// NewPipeline assembles the ranking pipeline from the generators and scorer
+// named in the config.
+func NewPipeline(cfg *config.Config, searcher Searcher, cf CFSource, catalog Catalog) (*ranking.Pipeline, error) {
+	scorer, err := NewScorer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return BuildPipeline(cfg, searcher, cf, catalog, cfg.RankGenerators, scorer)
+}
+
+// BuildPipeline assembles a ranking pipeline over the given sources that
+// generates candidates with the named generators and ranks them with scorer.
+// Every feature is always extracted so any of them can be weighted per
+// request.
+func BuildPipeline(cfg *config.Config, searcher Searcher, cf CFSource, catalog Catalog, defaults []string, scorer ranking.Scorer) (*ranking.Pipeline, error) {
+	vector := &VectorGenerator{searcher: searcher}
+	generators := []ranking.Generator{
+		vector,
+		&CFGenerator{cf: cf},
+		&PopularGenerator{catalog: catalog},
+		&RandomGenerator{catalog: catalog},
+	}
+
+	for _, name := range defaults {
+		if !containsGenerator(generators, name) {
+			return nil, fmt.Errorf("unknown candidate generator %q", name)
+		}
+	}
+
+	return &ranking.Pipeline{
+		Generators: generators,
+		Defaults:   defaults,
+		Fallback:   vector,
+		Extractors: []ranking.Extractor{
+			ranking.SimilarityExtractor{},
+			ranking.QualityExtractor{},
+			ranking.PopularityExtractor{},
+			ranking.FreshnessExtractor{HalfLife: cfg.FreshnessHalfLife},
+			&CFExtractor{cf: cf},
+		},
+		Scorer: scorer,
+	}, nil
+}
+
+// NewScorer returns the scorer named by RANK_SCORER.
+func NewScorer(cfg *config.Config) (ranking.Scorer, error) {
+	switch cfg.RankScorer {
+	case "weighted":
+		return ConfiguredWeights(cfg), nil
+	case "learned":
+		return ranking.LoadLogisticScorer(cfg.RankModelFile)
+	default:
+		return nil, fmt.Errorf("unknown ranking scorer %q", cfg.RankScorer)
+	}
+}
+
+// ConfiguredWeights returns the feature weights from the config.
+func ConfiguredWeights(cfg *config.Config) ranking.WeightedScorer {
+	return ranking.WeightedScorer{
+		"similarity": cfg.RankWeightSimilarity,
+		"quality":    cfg.RankWeightQuality,
+		"popularity": cfg.RankWeightPopularity,
+		"cf":         cfg.RankWeightCF,
+		"freshness":  cfg.RankWeightFreshness,
+	}
+}
+
+// ServeOptions are how the games served are picked from a ranking.
+type ServeOptions struct {
+	Diversity   float64
+	MaxPerTag   int
+	ExploreRate float64
+}
+
+// DefaultServeOptions are what requests that don't set their own get.
+func DefaultServeOptions(cfg *config.Config) ServeOptions {
+	return ServeOptions{Diversity: cfg.DefaultDiversity, ExploreRate: cfg.ExploreRate}
+}
+
+// PoolSize returns how many candidates to rank for req to serve size games
+// with opts. Re-ranking, diversity, Thompson sampling and quality priors need
+// a bigger pool than they return to have something to pick from.
+func PoolSize(cfg *config.Config, pipeline *ranking.Pipeline, req *ranking.Request, size int, opts ServeOptions) int {
+	thompson := cfg.ExploreStrategy == "thompson" && opts.ExploreRate > 0
+	if opts.Diversity > 0 || opts.MaxPerTag > 0 || thompson || pipeline.Reranks(req) {
+		return size * max(cfg.RerankPoolFactor, 1)
+	}
+	return size
+}
+
+func containsGenerator(generators []ranking.Generator, name string) bool {
+	for _, g := range generators {
+		if g.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VectorGenerator searches around each of the request's vectors, one channel
+// per vector.
+type VectorGenerator struct {
+	searcher Searcher
+}
+
+func (vg *VectorGenerator) Name() string { return "vector" }
+
+func (vg *VectorGenerator) Generate(ctx context.Context, req *ranking.Request) ([][]models.ScoredGame, error) {
+	channels := make([][]models.ScoredGame, 0, len(req.Vectors))
+	for _, vector := range req.Vectors {
+		scored, err := vg.searcher.Search(ctx, vector, req.PoolSize, req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, scored)
+	}
+	return channels, nil
+}
+
+// CFGenerator proposes the games most liked by users with the same likes.
+type CFGenerator struct {
+	cf CFSource
+}
+
+func (cg *CFGenerator) Name() string { return "cf" }
+
+func (cg *CFGenerator) Generate(ctx context.Context, req *ranking.Request) ([][]models.ScoredGame, error) {
+	scored, err := cg.cf.Recommend(ctx, req.UserID, req.PoolSize, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return [][]models.ScoredGame{scored}, nil
+}
+
+// PopularGenerator proposes the most reviewed games.
+type PopularGenerator struct {
+	catalog Catalog
+}
+
+func (pg *PopularGenerator) Name() string { return "popular" }
+
+func (pg *PopularGenerator) Generate(ctx context.Context, req *ranking.Request) ([][]models.ScoredGame, error) {
+	games, err := pg.catalog.GetPopular(ctx, req.PoolSize, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return [][]models.ScoredGame{toScored(games)}, nil
+}
+
+// RandomGenerator proposes games at random.
+type RandomGenerator struct {
+	catalog Catalog
+}
+
+func (rg *RandomGenerator) Name() string { return "random" }
+
+func (rg *RandomGenerator) Generate(ctx context.Context, req *ranking.Request) ([][]models.ScoredGame, error) {
+	games, err := rg.catalog.GetRandom(ctx, req.PoolSize, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return [][]models.ScoredGame{toScored(games)}, nil
+}
+
+// CFExtractor is a candidate's collaborative filtering score.
+type CFExtractor struct {
+	cf CFSource
+}
+
+func (ce *CFExtractor) Name() string { return "cf" }
+
+func (ce *CFExtractor) Extract(ctx context.Context, req *ranking.Request, candidates []models.ScoredGame) ([]float64, error) {
+	values := make([]float64, len(candidates))
+	if req.UserID == "" {
+		return values, nil
+	}
+
+	scores, err := ce.cf.Scores(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range candidates {
+		values[i] = scores[c.AppId]
+	}
+	return values, nil
+}
+
+func toScored(games []models.Game) []models.ScoredGame {
+	scored := make([]models.ScoredGame, 0, len(games))
+	for _, game := range games {
+		scored = append(scored, models.ScoredGame{Game: game})
+	}
+	return scored
+}
@@ -21,6 +21,7 @@ type Populator struct {
 	SteamSpyDetailsFormat string
 	SteamAPIDetailsFormat string
 	Vectorizer            *Vectorizer
+	Encoder               *FeatureEncoder
 }
 
 func NewPopulator(cfg *config.Config) *Populator {
@@ -30,6 +31,7 @@ func NewPopulator(cfg *config.Config) *Populator {
 		SteamSpyDetailsFormat: cfg.SteamSpyDetailsFormat,
 		SteamAPIDetailsFormat: cfg.SteamAPIDetailsFormat,
 		Vectorizer:            NewVectorizer(cfg),
+		Encoder:               NewFeatureEncoder(cfg),
 	}
 }
 
@@ -101,7 +103,7 @@ func (p *Populator) Populate(gr *repository.GameRepository, gmr *repository.Game
 				continue
 			}
 
-			gameEntry, err := createGameEntry(game, gameDetailsSpy, otherDetails.Data, appIDKey, p.Vectorizer)
+			gameEntry, err := createGameEntry(game, gameDetailsSpy, otherDetails.Data, appIDKey, p.Vectorizer, p.Encoder)
 			if err != nil {
 				log.Printf("Error creating game entry for appID %v: %v\n", appIDKey, err)
 				continue
@@ -127,7 +129,7 @@ func (p *Populator) Populate(gr *repository.GameRepository, gmr *repository.Game
 	}
 }
 
-func createGameEntry(game models.SteamspyResponse, gameDetailsSpy models.SteamspyDetails, gameDetailsApi models.SteamAPIDetails, appIDKey string, vectorizer *Vectorizer) (*models.Game, error) {
+func createGameEntry(game models.SteamspyResponse, gameDetailsSpy models.SteamspyDetails, gameDetailsApi models.SteamAPIDetails, appIDKey string, vectorizer *Vectorizer, encoder *FeatureEncoder) (*models.Game, error) {
 	intPrice, _ := strconv.Atoi(game.Price)
 	intInitialPrice, _ := strconv.Atoi(game.InitialPrice)
 	intDiscount, _ := strconv.Atoi(game.Discount)
@@ -144,21 +146,30 @@ func createGameEntry(game models.SteamspyResponse, gameDetailsSpy models.Steamsp
 		return nil, err
 	}
 
+	parentAppId, _ := strconv.Atoi(gameDetailsApi.FullGame.AppID)
+
 	gameEntry := &models.Game{
-		AppId:         game.AppID,
-		Name:          game.Name,
-		ShortDesc:     gameDetailsApi.ShortDesc,
-		Price:         intPrice,
-		InitialPrice:  intInitialPrice,
-		Discount:      intDiscount,
-		ReleaseDate:   release_date,
-		Genres:        strings.Split(gameDetailsSpy.Genres, " "),
-		Tags:          gameDetailsSpy.Tags,
-		Positive:      game.Positive,
-		Negative:      game.Negative,
-		Platforms:     platforms,
-		FeatureVector: vectorizer.Vectorize(gameDetailsSpy.Genres, gameDetailsSpy.Tags, gameDetailsApi.ShortDesc),
+		AppId:        game.AppID,
+		Name:         game.Name,
+		ShortDesc:    gameDetailsApi.ShortDesc,
+		Price:        intPrice,
+		InitialPrice: intInitialPrice,
+		Discount:     intDiscount,
+		ReleaseDate:  release_date,
+		Genres:       strings.Split(gameDetailsSpy.Genres, " "),
+		Tags:         gameDetailsSpy.Tags,
+		Positive:     game.Positive,
+		Negative:     game.Negative,
+		Platforms:    platforms,
+		Developers:   gameDetailsApi.Developers,
+		DLC:          gameDetailsApi.DLC,
+		ParentAppId:  parentAppId,
+	}
+	semantic, err := vectorizer.Vectorize(gameDetailsSpy.Genres, gameDetailsSpy.Tags, gameDetailsApi.ShortDesc)
+	if err != nil {
+		return nil, err
 	}
+	gameEntry.FeatureVector = encoder.Encode(gameEntry, semantic)
 
 	return gameEntry, nil
 }
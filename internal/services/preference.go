@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// PreferenceUpdater maintains user preference vectors as an exponentially
+// time-decayed sum of the feature vectors of the games they swiped on:
+//
+//	state(t) = sum over swipes i of weight_i * 0.5^((t - t_i) / halfLife) * vector_i
+//
+// The state is stored along with the time it was computed at, so each update
+// only decays it by the time elapsed and adds the new swipes. The preference
+// vector is the normalized state. Onboarded users also carry a seed vector
+// that decays as if it were a swipe made at onboarding, and users from before
+// swipes were recorded as events a baseline holding what they had learned,
+// decaying from when it was carried over. The swipe events are the source of
+// truth: undoing swipes deletes them and rebuilds the state from the ones
+// left.
+type PreferenceUpdater struct {
+	ur         *repository.UserRepository
+	gr         *repository.GameRepository
+	interests  *InterestBuilder
+	cache      *RecommendationCache
+	weights    map[models.SwipeAction]float64
+	seedWeight float64
+	sampleSize int
+	halfLife   time.Duration
+	dim        int
+}
+
+func NewPreferenceUpdater(cfg *config.Config, ur *repository.UserRepository, gr *repository.GameRepository, interests *InterestBuilder, cache *RecommendationCache) *PreferenceUpdater {
+	return &PreferenceUpdater{
+		ur:         ur,
+		gr:         gr,
+		interests:  interests,
+		cache:      cache,
+		weights:    SwipeWeights(cfg),
+		seedWeight: cfg.OnboardSeedWeight,
+		sampleSize: cfg.OnboardSampleSize,
+		halfLife:   cfg.PreferenceHalfLife,
+		dim:        FeatureDim(cfg),
+	}
+}
+
+// SwipeWeights returns how much each swipe action counts towards a preference.
+func SwipeWeights(cfg *config.Config) map[models.SwipeAction]float64 {
+	return map[models.SwipeAction]float64{
+		models.SwipeLike:      cfg.SwipeWeights.Like,
+		models.SwipeDislike:   cfg.SwipeWeights.Dislike,
+		models.SwipeSkip:      cfg.SwipeWeights.Skip,
+		models.SwipeSuperlike: cfg.SwipeWeights.Superlike,
+		models.SwipeWishlist:  cfg.SwipeWeights.Wishlist,
+	}
+}
+
+// PreferenceFromSwipes computes the preference vector a user with the given
+// swipes and no onboarding seed has at now, looking feature vectors up in
+// vectors. It matches what PreferenceUpdater.Rebuild stores.
+func PreferenceFromSwipes(cfg *config.Config, swipes []models.SwipeEvent, vectors map[int][]float64, now time.Time) []float64 {
+	dim := FeatureDim(cfg)
+	state := addDecayed(make([]float64, dim), swipes, vectors, SwipeWeights(cfg), cfg.PreferenceHalfLife, dim, now)
+	return utils.NormalizeVector(state)
+}
+
+// Apply records the swipes and folds them into the user's preference,
+// returning the new preference vector. Swipes a request already recorded for
+// the same game are ignored, so clients can safely retry.
+func (pu *PreferenceUpdater) Apply(ctx context.Context, id string, swipes []models.SwipeEvent) ([]float64, error) {
+	return pu.update(ctx, id, func(tx *repository.PreferenceTx) ([]float64, error) {
+		swipes, err := tx.InsertSwipes(ctx, swipes)
+		if err != nil {
+			return nil, err
+		}
+		if len(swipes) == 0 {
+			return tx.GetPreference(ctx)
+		}
+
+		state, updatedAt, err := tx.GetState(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if updatedAt == nil || len(state) != pu.dim {
+			// The swipes were already recorded and legacy preferences carried
+			// over as a baseline, so a rebuild includes them
+			return pu.rebuild(ctx, tx, now)
+		}
+
+		state = utils.ScaleVector(state, pu.decay(now.Sub(*updatedAt)))
+		state, err = pu.addSwipes(ctx, state, swipes, now)
+		if err != nil {
+			return nil, err
+		}
+
+		return pu.save(ctx, tx, state, now)
+	})
+}
+
+// Onboard seeds a new user's preference from the centroid of games matching
+// their favourite tags and genres, weighted like OnboardSeedWeight likes, and
+// records the games they picked as likes.
+func (pu *PreferenceUpdater) Onboard(ctx context.Context, id string, tags []string, genres []string, appIds []int) ([]float64, error) {
+	now := time.Now()
+
+	var seed []float64
+	if len(tags) > 0 || len(genres) > 0 {
+		games, err := pu.gr.GetRandom(ctx, pu.sampleSize, &models.GameFilter{
+			PriceRange: &models.PriceRange{Min: 0, Max: math.MaxInt32},
+			Tags:       tags,
+			Genres:     genres,
+		})
+		if err != nil {
+			return nil, err
+		}
+		seed = pu.centroid(games)
+	}
+
+	swipes := make([]models.SwipeEvent, 0, len(appIds))
+	for _, appId := range appIds {
+		swipes = append(swipes, models.SwipeEvent{UserID: id, AppID: appId, Action: models.SwipeLike, CreatedAt: now})
+	}
+
+	return pu.update(ctx, id, func(tx *repository.PreferenceTx) ([]float64, error) {
+		if err := tx.SetSeed(ctx, seed, now); err != nil {
+			return nil, err
+		}
+		if _, err := tx.InsertSwipes(ctx, swipes); err != nil {
+			return nil, err
+		}
+		return pu.rebuild(ctx, tx, now)
+	})
+}
+
+// Undo deletes the user's n latest swipes, or the swipes recorded by
+// requestId when it is set, and recomputes the preference from the onboarding
+// seed, baseline and the remaining swipes. It returns the undone swipes,
+// latest first, and the new preference vector.
+func (pu *PreferenceUpdater) Undo(ctx context.Context, id string, n int, requestId string) ([]models.SwipeEvent, []float64, error) {
+	var undone []models.SwipeEvent
+	preferences, err := pu.update(ctx, id, func(tx *repository.PreferenceTx) ([]float64, error) {
+		var err error
+		if requestId != "" {
+			undone, err = tx.DeleteRequest(ctx, requestId)
+		} else {
+			undone, err = tx.DeleteLast(ctx, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(undone) == 0 {
+			return tx.GetPreference(ctx)
+		}
+		return pu.rebuild(ctx, tx, time.Now())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(undone, func(i, j int) bool { return undone[i].CreatedAt.After(undone[j].CreatedAt) })
+	return undone, preferences, nil
+}
+
+// centroid returns the normalized mean of the games' feature vectors, or nil
+// if none of them has one.
+func (pu *PreferenceUpdater) centroid(games []models.Game) []float64 {
+	sum := make([]float64, pu.dim)
+	n := 0
+	for _, game := range games {
+		if len(game.FeatureVector) != pu.dim {
+			continue
+		}
+		sum, _ = utils.AddVectors(sum, game.FeatureVector)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return utils.NormalizeVector(sum)
+}
+
+// Rebuild recomputes the user's preference from their onboarding seed,
+// baseline and full swipe history.
+func (pu *PreferenceUpdater) Rebuild(ctx context.Context, id string) ([]float64, error) {
+	return pu.update(ctx, id, func(tx *repository.PreferenceTx) ([]float64, error) {
+		return pu.rebuild(ctx, tx, time.Now())
+	})
+}
+
+// update runs fn in a transaction holding the lock on the user's row, then
+// drops what was derived from their old preference once it commits.
+func (pu *PreferenceUpdater) update(ctx context.Context, id string, fn func(tx *repository.PreferenceTx) ([]float64, error)) ([]float64, error) {
+	tx, err := pu.ur.BeginPreferenceUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := pu.carryOver(ctx, tx); err != nil {
+		return nil, err
+	}
+	preferences, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	pu.cache.Invalidate(id)
+	pu.interests.Schedule(id)
+	return preferences, nil
+}
+
+// carryOver keeps the preference of users from before swipes were recorded
+// as events, who only have its running sum, as their baseline the first time
+// it is updated, so rebuilding from their events doesn't lose it.
+func (pu *PreferenceUpdater) carryOver(ctx context.Context, tx *repository.PreferenceTx) error {
+	_, updatedAt, err := tx.GetState(ctx)
+	if err != nil || updatedAt != nil {
+		return err
+	}
+	_, baselineAt, err := tx.GetBaseline(ctx)
+	if err != nil || baselineAt != nil {
+		return err
+	}
+
+	legacy, err := tx.GetPreference(ctx)
+	if err != nil || !slices.ContainsFunc(legacy, func(v float64) bool { return v != 0 }) {
+		// New users start from a zero vector, which there is nothing to keep of
+		return err
+	}
+	return tx.SetBaseline(ctx, legacy, time.Now())
+}
+
+func (pu *PreferenceUpdater) rebuild(ctx context.Context, tx *repository.PreferenceTx, now time.Time) ([]float64, error) {
+	events, err := tx.GetSwipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, seededAt, err := tx.GetSeed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline, baselineAt, err := tx.GetBaseline(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make([]float64, pu.dim)
+	if seededAt != nil && len(seed) == pu.dim {
+		state = utils.ScaleVector(seed, pu.seedWeight*pu.decay(now.Sub(*seededAt)))
+	}
+	if baselineAt != nil && len(baseline) == pu.dim {
+		state, _ = utils.AddVectors(state, utils.ScaleVector(baseline, pu.decay(now.Sub(*baselineAt))))
+	}
+
+	state, err = pu.addSwipes(ctx, state, events, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return pu.save(ctx, tx, state, now)
+}
+
+// addSwipes adds each swipe's weighted feature vector to state, decayed by
+// the time between the swipe and now.
+func (pu *PreferenceUpdater) addSwipes(ctx context.Context, state []float64, swipes []models.SwipeEvent, now time.Time) ([]float64, error) {
+	appIds := make([]int, 0, len(swipes))
+	for _, swipe := range swipes {
+		appIds = append(appIds, swipe.AppID)
+	}
+
+	vectors, err := pu.gr.GetFeatureVecMap(ctx, appIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return addDecayed(state, swipes, vectors, pu.weights, pu.halfLife, pu.dim, now), nil
+}
+
+// addDecayed adds each swipe's weighted feature vector to state, decayed by
+// the time between the swipe and now. Games without a dim-long vector are
+// skipped.
+func addDecayed(state []float64, swipes []models.SwipeEvent, vectors map[int][]float64, weights map[models.SwipeAction]float64, halfLife time.Duration, dim int, now time.Time) []float64 {
+	for _, swipe := range swipes {
+		vector, ok := vectors[swipe.AppID]
+		if !ok || len(vector) != dim {
+			continue
+		}
+		weight := weights[swipe.Action] * decay(halfLife, now.Sub(swipe.CreatedAt))
+		state, _ = utils.AddVectors(state, utils.ScaleVector(vector, weight))
+	}
+	return state
+}
+
+func (pu *PreferenceUpdater) save(ctx context.Context, tx *repository.PreferenceTx, state []float64, now time.Time) ([]float64, error) {
+	preferences := utils.NormalizeVector(state)
+	if err := tx.SetState(ctx, state, now, preferences); err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+func (pu *PreferenceUpdater) decay(elapsed time.Duration) float64 {
+	return decay(pu.halfLife, elapsed)
+}
+
+// decay returns the factor a contribution shrinks by over elapsed. A
+// non-positive half-life disables decay.
+func decay(halfLife time.Duration, elapsed time.Duration) float64 {
+	if halfLife <= 0 || elapsed <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(elapsed) / float64(halfLife))
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+)
+
+// RecommendationCache keeps the rankings of recent recommendation requests,
+// so repeat requests skip loading the preference and ranking the catalog.
+// Only appids, scores and breakdowns are kept; callers look the games up
+// again. Entries are keyed by user and request, live for ttl and are evicted
+// least recently used first past size. Invalidate must be called whenever a
+// user's preference or exclusions change and Purge whenever the catalog does.
+type RecommendationCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+	byUser  map[string]map[string]*list.Element
+	// Sequence number of each user's latest invalidation, so lookups made
+	// before it can't store. Invalidations older than ttl are dropped and
+	// folded into forgotten: lookups made before it can't store for anyone.
+	invalidated map[string]int64
+	expiring    []invalidation // oldest first
+	seq         int64
+	forgotten   int64
+	// Bumped by Purge, so lookups made before it can't store
+	generation int64
+
+	hits          int64
+	misses        int64
+	evictions     int64
+	invalidations int64
+	purges        int64
+}
+
+type cacheEntry struct {
+	key     string
+	user    string
+	ranking *CachedRanking
+	expires time.Time
+}
+
+// CachedRanking is what a recommendation request was ranked with and the
+// candidates it ranked, best first.
+type CachedRanking struct {
+	// Vectors the candidates were compared against
+	Vectors    [][]float64
+	Candidates []CachedCandidate
+}
+
+type CachedCandidate struct {
+	AppID     int
+	Score     float64
+	Breakdown map[string]float64
+}
+
+type invalidation struct {
+	user string
+	seq  int64
+	at   time.Time
+}
+
+// CacheVersion is the state of the cache a lookup saw. Results computed
+// after a miss are only stored if it is still current.
+type CacheVersion struct {
+	seq        int64
+	generation int64
+}
+
+type CacheStats struct {
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`
+	Evictions     int64   `json:"evictions"`
+	Invalidations int64   `json:"invalidations"`
+	Purges        int64   `json:"purges"`
+}
+
+// NewRecommendationCache returns a cache of up to REC_CACHE_SIZE requests. A
+// non-positive size disables caching.
+func NewRecommendationCache(cfg *config.Config) *RecommendationCache {
+	return &RecommendationCache{
+		size:        cfg.RecCacheSize,
+		ttl:         cfg.RecCacheTTL,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+		byUser:      make(map[string]map[string]*list.Element),
+		invalidated: make(map[string]int64),
+	}
+}
+
+// Get returns the ranking cached for the user's request with only the
+// candidates that pass keep, such as the games not seen since they were
+// ranked, along with the version to Put a fresh ranking with on a miss. Fewer
+// than minimum candidates passing counts as a miss.
+func (rc *RecommendationCache) Get(user string, request string, keep func(appId int) bool, minimum int) (*CachedRanking, CacheVersion, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	version := CacheVersion{seq: rc.seq, generation: rc.generation}
+	elem, ok := rc.entries[rc.key(user, request)]
+	if ok && time.Now().After(elem.Value.(*cacheEntry).expires) {
+		rc.remove(elem)
+		ok = false
+	}
+
+	var ranking *CachedRanking
+	if ok {
+		cached := elem.Value.(*cacheEntry).ranking
+		ranking = &CachedRanking{Vectors: cached.Vectors}
+		for _, c := range cached.Candidates {
+			if keep(c.AppID) {
+				ranking.Candidates = append(ranking.Candidates, c)
+			}
+		}
+		ok = len(ranking.Candidates) >= minimum
+	}
+	if !ok {
+		rc.misses++
+		return nil, version, false
+	}
+
+	rc.hits++
+	rc.order.MoveToFront(elem)
+	return ranking, version, true
+}
+
+// Put caches the ranking of the user's request, unless the user's preference
+// or the catalog changed since the Get that returned version.
+func (rc *RecommendationCache) Put(user string, request string, version CacheVersion, vectors [][]float64, candidates []models.ScoredGame) {
+	if rc.size <= 0 {
+		return
+	}
+
+	ranking := &CachedRanking{Vectors: vectors, Candidates: make([]CachedCandidate, 0, len(candidates))}
+	for _, c := range candidates {
+		ranking.Candidates = append(ranking.Candidates, CachedCandidate{AppID: c.AppId, Score: c.Score, Breakdown: c.Breakdown})
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if version.generation != rc.generation || rc.invalidatedSince(user, version.seq) {
+		return
+	}
+
+	key := rc.key(user, request)
+	if elem, ok := rc.entries[key]; ok {
+		rc.remove(elem)
+	}
+
+	elem := rc.order.PushFront(&cacheEntry{key: key, user: user, ranking: ranking, expires: time.Now().Add(rc.ttl)})
+	rc.entries[key] = elem
+	if rc.byUser[user] == nil {
+		rc.byUser[user] = make(map[string]*list.Element)
+	}
+	rc.byUser[user][key] = elem
+
+	for rc.order.Len() > rc.size {
+		rc.remove(rc.order.Back())
+		rc.evictions++
+	}
+}
+
+// Invalidate drops everything cached for the user, and stops rankings from
+// lookups made before it from being stored.
+func (rc *RecommendationCache) Invalidate(user string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	rc.seq++
+	rc.invalidated[user] = rc.seq
+	rc.expiring = append(rc.expiring, invalidation{user: user, seq: rc.seq, at: now})
+	for len(rc.expiring) > 0 && now.Sub(rc.expiring[0].at) > rc.ttl {
+		old := rc.expiring[0]
+		if rc.invalidated[old.user] == old.seq {
+			delete(rc.invalidated, old.user)
+		}
+		rc.forgotten = old.seq
+		rc.expiring = rc.expiring[1:]
+	}
+
+	for _, elem := range rc.byUser[user] {
+		rc.remove(elem)
+	}
+	rc.invalidations++
+}
+
+// Purge drops every entry, for when the catalog or other users' data that
+// ranking depends on changes.
+func (rc *RecommendationCache) Purge() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.order.Init()
+	rc.entries = make(map[string]*list.Element)
+	rc.byUser = make(map[string]map[string]*list.Element)
+	rc.generation++
+	rc.purges++
+}
+
+func (rc *RecommendationCache) Stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	stats := CacheStats{
+		Entries:       rc.order.Len(),
+		Hits:          rc.hits,
+		Misses:        rc.misses,
+		Evictions:     rc.evictions,
+		Invalidations: rc.invalidations,
+		Purges:        rc.purges,
+	}
+	if lookups := rc.hits + rc.misses; lookups > 0 {
+		stats.HitRate = float64(rc.hits) / float64(lookups)
+	}
+	return stats
+}
+
+// invalidatedSince reports whether the user may have been invalidated after
+// the lookup that saw seq.
+func (rc *RecommendationCache) invalidatedSince(user string, seq int64) bool {
+	if at, ok := rc.invalidated[user]; ok {
+		return at > seq
+	}
+	return rc.forgotten > seq
+}
+
+func (rc *RecommendationCache) key(user string, request string) string {
+	return user + "\x00" + request
+}
+
+func (rc *RecommendationCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	rc.order.Remove(elem)
+	delete(rc.entries, entry.key)
+	delete(rc.byUser[entry.user], entry.key)
+	if len(rc.byUser[entry.user]) == 0 {
+		delete(rc.byUser, entry.user)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ty4g1/gamescout_backend/internal/config"
+	"github.com/ty4g1/gamescout_backend/internal/models"
+	"github.com/ty4g1/gamescout_backend/internal/repository"
+	"github.com/ty4g1/gamescout_backend/internal/utils"
+)
+
+// Searcher finds the k games matching filter whose feature vectors are most
+// similar to a query vector, best match first.
+type Searcher interface {
+	Search(ctx context.Context, vector []float64, k int, filter *models.GameFilter) ([]models.ScoredGame, error)
+}
+
+// NewSearcher returns the Searcher for the configured SEARCH_BACKEND.
+func NewSearcher(cfg *config.Config, gr *repository.GameRepository) Searcher {
+	switch cfg.SearchBackend {
+	case "pgvector":
+		return &PgVectorSearcher{gr: gr}
+	case "ann":
+		return NewIndexSearcher(cfg, gr)
+	default:
+		return &ScanSearcher{catalog: gr}
+	}
+}
+
+// PgVectorSearcher runs the search in Postgres against the HNSW index.
+type PgVectorSearcher struct {
+	gr *repository.GameRepository
+}
+
+func (ps *PgVectorSearcher) Search(ctx context.Context, vector []float64, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+	return ps.gr.SearchByVector(ctx, vector, k, filter)
+}
+
+// ScanSearcher loads every matching game and ranks them in memory.
+type ScanSearcher struct {
+	catalog Catalog
+}
+
+func NewScanSearcher(catalog Catalog) *ScanSearcher {
+	return &ScanSearcher{catalog: catalog}
+}
+
+func (ss *ScanSearcher) Search(ctx context.Context, vector []float64, k int, filter *models.GameFilter) ([]models.ScoredGame, error) {
+	games, err := ss.catalog.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	top := utils.TopK(len(games), k, func(i int) (float64, error) {
+		return utils.ComputeSimilarity(vector, games[i].FeatureVector)
+	})
+
+	scored := make([]models.ScoredGame, 0, len(top))
+	for _, ranked := range top {
+		scored = append(scored, models.ScoredGame{Game: games[ranked.Index], Score: ranked.Score})
+	}
+
+	return scored, nil
+}
@@ -3,10 +3,9 @@ package services
 import (
 	"bytes"
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
-	"os"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/ty4g1/gamescout_backend/internal/config"
@@ -14,55 +13,99 @@ import (
 
 type Vectorizer struct {
 	MicroserviceURL string
+	VectorDim       int
+	TagTopN         int
+	TagMinVotes     int
+	TagMaxRepeat    int
 }
 
 func NewVectorizer(cfg *config.Config) *Vectorizer {
 	return &Vectorizer{
 		MicroserviceURL: cfg.MicroserviceURL,
+		VectorDim:       cfg.VectorDim,
+		TagTopN:         cfg.TagTopN,
+		TagMinVotes:     cfg.TagMinVotes,
+		TagMaxRepeat:    cfg.TagMaxRepeat,
 	}
 }
 
-func (v *Vectorizer) Vectorize(genres string, tags map[string]int, shortDesc string) []float64 {
-	VECTOR_DIM, err := strconv.Atoi(os.Getenv("VECTOR_DIM"))
-	if err != nil {
-		VECTOR_DIM = 384
-	}
-
-	keys := make([]string, 0, len(tags))
-	for k := range tags {
-		keys = append(keys, k)
-	}
-
+// Vectorize returns the semantic embedding of a game from the microservice.
+// It fails rather than return a made up vector, so games it can't embed are
+// left for the next populate run to retry.
+func (v *Vectorizer) Vectorize(genres string, tags map[string]int, shortDesc string) ([]float64, error) {
 	requestBody := &struct {
 		Genres      string `json:"genres"`
 		Tags        string `json:"tags"`
 		Description string `json:"description"`
 	}{
 		Genres:      genres,
-		Tags:        strings.Join(keys, " "),
+		Tags:        v.tagText(tags),
 		Description: shortDesc,
 	}
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Printf("Error parsing into JSON: %v\n", err)
-		return make([]float64, VECTOR_DIM)
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
 	}
 
 	resp, err := http.Post(v.MicroserviceURL, "application/json", bytes.NewReader(jsonData))
 	if err != nil {
-		log.Printf("Error getting feature vector: %v\n", err)
-		return make([]float64, VECTOR_DIM)
+		return nil, fmt.Errorf("failed to get feature vector: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding service returned %s", resp.Status)
+	}
 
 	var serviceResponse struct {
 		Vector []float64 `json:"vector"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&serviceResponse); err != nil {
-		log.Printf("Error parsing JSON: %v\n", err)
-		return make([]float64, VECTOR_DIM)
+		return nil, fmt.Errorf("failed to parse feature vector: %w", err)
+	}
+	if len(serviceResponse.Vector) != v.VectorDim {
+		return nil, fmt.Errorf("embedding service returned %d dimensions, expected %d", len(serviceResponse.Vector), v.VectorDim)
+	}
+
+	return serviceResponse.Vector, nil
+}
+
+// tagText builds the tag input for the embedding model. Tags are ordered by
+// vote count (ties broken alphabetically) so the same game always yields the
+// same text, and higher-voted tags are repeated to give them more weight.
+func (v *Vectorizer) tagText(tags map[string]int) string {
+	keys := make([]string, 0, len(tags))
+	for k, votes := range tags {
+		if votes >= v.TagMinVotes {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if tags[keys[i]] != tags[keys[j]] {
+			return tags[keys[i]] > tags[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if v.TagTopN > 0 && len(keys) > v.TagTopN {
+		keys = keys[:v.TagTopN]
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	maxVotes := tags[keys[0]]
+	words := make([]string, 0, len(keys))
+	for _, k := range keys {
+		repeat := 1
+		if v.TagMaxRepeat > 1 && maxVotes > 0 {
+			repeat += (v.TagMaxRepeat - 1) * tags[k] / maxVotes
+		}
+		for range repeat {
+			words = append(words, k)
+		}
 	}
 
-	return serviceResponse.Vector
+	return strings.Join(words, " ")
 }
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTagText(t *testing.T) {
+	tests := []struct {
+		name      string
+		topN      int
+		minVotes  int
+		maxRepeat int
+		tags      map[string]int
+		want      string
+	}{
+		{
+			name: "ordered by votes then name",
+			tags: map[string]int{"b": 5, "c": 1, "a": 5, "d": 7},
+			want: "d a b c",
+		},
+		{
+			name: "top n",
+			topN: 2,
+			tags: map[string]int{"a": 10, "b": 8, "c": 6, "d": 4},
+			want: "a b",
+		},
+		{
+			name:     "minimum votes",
+			minVotes: 3,
+			tags:     map[string]int{"a": 10, "b": 3, "c": 1},
+			want:     "a b",
+		},
+		{
+			name:      "repeated by votes",
+			maxRepeat: 3,
+			tags:      map[string]int{"a": 10, "b": 5, "c": 1},
+			want:      "a a a b b c",
+		},
+		{
+			name:      "minimum votes before top n",
+			topN:      3,
+			minVotes:  5,
+			maxRepeat: 2,
+			tags:      map[string]int{"a": 100, "b": 50, "c": 20, "d": 10, "e": 1},
+			want:      "a a b c",
+		},
+		{
+			name:     "nothing left",
+			minVotes: 5,
+			tags:     map[string]int{"a": 1},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Vectorizer{TagTopN: tt.topN, TagMinVotes: tt.minVotes, TagMaxRepeat: tt.maxRepeat}
+
+			// The same tags inserted in another order iterate differently
+			keys := make([]string, 0, len(tt.tags))
+			for tag := range tt.tags {
+				keys = append(keys, tag)
+			}
+			slices.Sort(keys)
+			slices.Reverse(keys)
+			reordered := make(map[string]int, len(keys))
+			for _, tag := range keys {
+				reordered[tag] = tt.tags[tag]
+			}
+
+			for range 10 {
+				for _, tags := range []map[string]int{tt.tags, reordered} {
+					if got := v.tagText(tags); got != tt.want {
+						t.Fatalf("tagText = %q, want %q", got, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
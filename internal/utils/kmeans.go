@@ -0,0 +1,78 @@
+package utils
+
+// SphericalKMeans clusters normalized vectors into at most k groups by cosine
+// similarity and returns the unit-length centroids along with the cluster of
+// each vector. Seeding is deterministic: the first vector, then repeatedly
+// the vector least similar to every centroid chosen so far. Clusters that end
+// up empty are dropped and the assignments renumbered.
+func SphericalKMeans(vectors [][]float64, k int, iterations int) ([][]float64, []int) {
+	k = min(k, len(vectors))
+	if k <= 0 {
+		return nil, nil
+	}
+
+	centroids := [][]float64{vectors[0]}
+	closest := make([]float64, len(vectors))
+	for i, v := range vectors {
+		closest[i], _ = ComputeSimilarity(v, centroids[0])
+	}
+	for len(centroids) < k {
+		next := 0
+		for i := range vectors {
+			if closest[i] < closest[next] {
+				next = i
+			}
+		}
+		centroids = append(centroids, vectors[next])
+		for i, v := range vectors {
+			if sim, _ := ComputeSimilarity(v, vectors[next]); sim > closest[i] {
+				closest[i] = sim
+			}
+		}
+	}
+
+	assignments := make([]int, len(vectors))
+	for range iterations {
+		changed := false
+		for i, v := range vectors {
+			best, bestSim := 0, -2.0
+			for c, centroid := range centroids {
+				if sim, _ := ComputeSimilarity(v, centroid); sim > bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+
+		sums := make([][]float64, len(centroids))
+		for i, v := range vectors {
+			sums[assignments[i]], _ = AddVectors(sums[assignments[i]], v)
+		}
+		for c := range centroids {
+			if sums[c] != nil {
+				centroids[c] = NormalizeVector(sums[c])
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	// Drop empty clusters
+	renumber := make(map[int]int)
+	var kept [][]float64
+	for i := range assignments {
+		c := assignments[i]
+		if _, ok := renumber[c]; !ok {
+			renumber[c] = len(kept)
+			kept = append(kept, centroids[c])
+		}
+		assignments[i] = renumber[c]
+	}
+
+	return kept, assignments
+}
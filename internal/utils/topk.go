@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Below this many items per worker, goroutine overhead outweighs the gain
+const minItemsPerWorker = 2048
+
+// Ranked is the position of a scored item in the input of TopK.
+type Ranked struct {
+	Index int
+	Score float64
+}
+
+// TopK calls score once for each of the n items, spread across all cores,
+// and returns the k highest scoring items, best first. Ties are broken by
+// index so the result is deterministic. Items whose score fails are skipped.
+func TopK(n int, k int, score func(i int) (float64, error)) []Ranked {
+	if n <= 0 || k <= 0 {
+		return nil
+	}
+
+	workers, chunk := split(n)
+	heaps := make([]rankedHeap, workers)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := make(rankedHeap, 0, k)
+			for i := w * chunk; i < min((w+1)*chunk, n); i++ {
+				s, err := score(i)
+				if err != nil {
+					continue
+				}
+				h.offer(Ranked{Index: i, Score: s}, k)
+			}
+			heaps[w] = h
+		}()
+	}
+	wg.Wait()
+
+	merged := make([]Ranked, 0, workers*k)
+	for _, h := range heaps {
+		merged = append(merged, h...)
+	}
+	sort.Slice(merged, func(i, j int) bool { return better(merged[i], merged[j]) })
+	if len(merged) > k {
+		merged = merged[:k]
+	}
+	return merged
+}
+
+// ParallelFor calls fn once for each of the n items, spread across all cores
+// the same way as TopK, and returns when every call has.
+func ParallelFor(n int, fn func(i int)) {
+	if n <= 0 {
+		return
+	}
+
+	workers, chunk := split(n)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w * chunk; i < min((w+1)*chunk, n); i++ {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// split divides n items into contiguous chunks, one per worker.
+func split(n int) (workers int, chunk int) {
+	workers = min(runtime.GOMAXPROCS(0), (n+minItemsPerWorker-1)/minItemsPerWorker)
+	return workers, (n + workers - 1) / workers
+}
+
+func better(a Ranked, b Ranked) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Index < b.Index
+}
+
+// rankedHeap is a min-heap with the worst kept item at the root, so a full
+// heap only needs to compare new items against h[0].
+type rankedHeap []Ranked
+
+func (h *rankedHeap) offer(r Ranked, k int) {
+	if len(*h) < k {
+		*h = append(*h, r)
+		h.up(len(*h) - 1)
+		return
+	}
+	if better(r, (*h)[0]) {
+		(*h)[0] = r
+		h.down(0)
+	}
+}
+
+func (h rankedHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !better(h[parent], h[i]) {
+			break
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+func (h rankedHeap) down(i int) {
+	for {
+		worst := i
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(h) && better(h[worst], h[child]) {
+				worst = child
+			}
+		}
+		if worst == i {
+			return
+		}
+		h[i], h[worst] = h[worst], h[i]
+		i = worst
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestTopKOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Enough items to be split across several workers
+	scores := make([]float64, 10*minItemsPerWorker)
+	for i := range scores {
+		scores[i] = rng.Float64()
+	}
+
+	got := TopK(len(scores), 25, func(i int) (float64, error) { return scores[i], nil })
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	if len(got) != 25 {
+		t.Fatalf("got %d items, want 25", len(got))
+	}
+	for i, r := range got {
+		if r.Index != order[i] || r.Score != scores[order[i]] {
+			t.Errorf("item %d = %+v, want index %d score %f", i, r, order[i], scores[order[i]])
+		}
+	}
+}
+
+func TestTopKTies(t *testing.T) {
+	scores := []float64{1, 3, 2, 3, 1, 3, 2}
+
+	got := TopK(len(scores), 5, func(i int) (float64, error) { return scores[i], nil })
+
+	want := []int{1, 3, 5, 2, 6}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.Index != want[i] {
+			t.Errorf("item %d has index %d, want %d", i, r.Index, want[i])
+		}
+	}
+}
+
+func TestTopKMoreThanN(t *testing.T) {
+	scores := []float64{0.5, 0.9, 0.1}
+
+	got := TopK(len(scores), 10, func(i int) (float64, error) { return scores[i], nil })
+
+	want := []int{1, 0, 2}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.Index != want[i] {
+			t.Errorf("item %d has index %d, want %d", i, r.Index, want[i])
+		}
+	}
+}
+
+func TestTopKSkipsErrors(t *testing.T) {
+	errSkip := errors.New("skip")
+
+	got := TopK(6, 10, func(i int) (float64, error) {
+		if i%2 == 1 {
+			return 0, errSkip
+		}
+		return float64(i), nil
+	})
+
+	want := []int{4, 2, 0}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i, r := range got {
+		if r.Index != want[i] {
+			t.Errorf("item %d has index %d, want %d", i, r.Index, want[i])
+		}
+	}
+}
+
+func TestTopKEmpty(t *testing.T) {
+	score := func(i int) (float64, error) { return 1, nil }
+	if got := TopK(0, 5, score); got != nil {
+		t.Errorf("TopK(0, 5) = %v, want nil", got)
+	}
+	if got := TopK(5, 0, score); got != nil {
+		t.Errorf("TopK(5, 0) = %v, want nil", got)
+	}
+}
+
+// BenchmarkTopK ranks synthetic catalogs by similarity to a query, as the
+// in-memory recommendation paths do. Vectors are kept short so the 1M
+// catalog fits in memory.
+func BenchmarkTopK(b *testing.B) {
+	const dim, k = 32, 10
+	rng := rand.New(rand.NewSource(1))
+	randomVector := func() []float64 {
+		v := make([]float64, dim)
+		for i := range v {
+			v[i] = rng.NormFloat64()
+		}
+		return NormalizeVector(v)
+	}
+	query := randomVector()
+
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		catalog := make([][]float64, n)
+		for i := range catalog {
+			catalog[i] = randomVector()
+		}
+
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				TopK(n, k, func(i int) (float64, error) {
+					return ComputeSimilarity(query, catalog[i])
+				})
+			}
+		})
+	}
+}
@@ -59,6 +59,14 @@ func SumRows(v [][]float64) ([]float64, error) {
 	return res, nil
 }
 
+func ScaleVector(v []float64, factor float64) []float64 {
+	res := make([]float64, len(v))
+	for i, val := range v {
+		res[i] = val * factor
+	}
+	return res
+}
+
 // In your utils package
 func NormalizeVector(v []float64) []float64 {
 	var magnitude float64